@@ -21,6 +21,9 @@ func main() {
 	inputFile := flag.String("input", "", "Input markdown file")
 	outputFile := flag.String("output", "", "Output HTML file")
 	serverFlag := flag.Bool("serve", false, "Serve the generated HTML file")
+	unsafeHTML := flag.Bool("unsafe-html", false, "Pass raw HTML in the markdown through unescaped")
+	tocSidebar := flag.Bool("toc-sidebar", false, "Show a table of contents next to the page")
+	math := flag.Bool("math", false, "Render $...$ and $$...$$ LaTeX math as MathML")
 	flag.Parse()
 
 	// debug input file
@@ -44,11 +47,18 @@ func main() {
 
 	renderer := &madopa.Renderer{}
 	renderer.SetEscapeHTML(true)
+	renderer.SetHardLineBreak(true)
+	renderer.SetUnsafeHTML(*unsafeHTML)
+	renderer.SetHeadingPermalinks(true)
+	renderer.SetTOCSidebar(*tocSidebar)
 	renderer.SetIncludeCss(true)
 	renderer.SetCssFilePath("./internal/renderer/styles/dark_blog.css")
 	renderer.SetSyntaxHighlight(true)
 
-	html, err := madopa.Convert(string(content), renderer.NewHTMLRenderer())
+	parser := &madopa.Parser{}
+	parser.SetMath(*math)
+
+	html, err := parser.Convert(string(content), renderer.NewHTMLRenderer())
 	if err != nil {
 		fmt.Printf("Error parsing markdow %v\n", err)
 		os.Exit(1)
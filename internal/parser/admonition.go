@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// alertMarker is the first line of a GitHub alert: "> [!NOTE]",
+	// optionally followed by a title.
+	alertMarker = regexp.MustCompile(`^[ \t]*\[!([A-Za-z]+)\](?:[ \t]+(.*?))?[ \t]*$`)
+	// admonitionFence opens or closes a fenced admonition: ":::warning
+	// Title" or ":::".
+	admonitionFence = regexp.MustCompile(`^ {0,3}(:{3,})[ \t]*(?:([A-Za-z][\w-]*)(?:[ \t]+(.*?))?)?[ \t]*$`)
+)
+
+// parseAlert recognises a GitHub alert in the content lines of a block
+// quote and returns it as an admonition.
+func (p *parser) parseAlert(lines []string, lazy map[int]bool) (*Admonition, bool, error) {
+	if !p.opts.Admonitions {
+		return nil, false, nil
+	}
+	match := alertMarker.FindStringSubmatch(lines[0])
+	if match == nil {
+		return nil, false, nil
+	}
+
+	// The marker line is dropped, so the lazy lines move up by one.
+	contentLazy := make(map[int]bool, len(lazy))
+	for i := range lazy {
+		if i > 0 {
+			contentLazy[i-1] = true
+		}
+	}
+	children, _, err := p.parseContainer(lines[1:], contentLazy)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &Admonition{
+		Kind:     strings.ToLower(match[1]),
+		Title:    match[2],
+		Children: children,
+	}, true, nil
+}
+
+// isAdmonitionFence reports whether line opens a fenced admonition.
+func (p *parser) isAdmonitionFence(line string) bool {
+	if !p.opts.Admonitions || !strings.HasPrefix(strings.TrimLeft(line, " "), ":::") {
+		return false
+	}
+	match := admonitionFence.FindStringSubmatch(line)
+	return match != nil && match[2] != ""
+}
+
+// parseFencedAdmonition parses an admonition fenced by ":::kind Title" and
+// ":::" lines. Admonitions nest; the closing fence needs at least as many
+// colons as the opening one. An unclosed admonition runs to the end of its
+// container.
+func (p *parser) parseFencedAdmonition() (*Admonition, error) {
+	opening := admonitionFence.FindStringSubmatch(p.line)
+	var lines []string
+	var fence *codeFence
+	depth := 0
+
+	for p.pos < len(p.input) {
+		p.readLine()
+
+		if fence != nil {
+			if fence.closedBy(p.line) {
+				fence = nil
+			}
+		} else if opened, ok := parseCodeFence(p.line); ok {
+			fence = &opened
+		} else if match := admonitionFence.FindStringSubmatch(p.line); match != nil {
+			if match[2] != "" {
+				depth++
+			} else if depth > 0 {
+				depth--
+			} else if len(match[1]) >= len(opening[1]) {
+				break
+			}
+		}
+
+		lines = append(lines, p.line)
+	}
+
+	children, _, err := p.parseContainer(lines, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Admonition{
+		Kind:     strings.ToLower(opening[2]),
+		Title:    opening[3],
+		Children: children,
+	}, nil
+}
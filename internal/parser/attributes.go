@@ -0,0 +1,213 @@
+package parser
+
+import "strings"
+
+// parseAttributeList parses the contents of a {...} attribute block made of
+// whitespace separated key=value pairs, where values may be quoted, as well as
+// the ".class" and "#id" shorthands. Keys without a value map to "".
+func parseAttributeList(s string) (map[string]string, bool) {
+	attributes := make(map[string]string)
+	i := 0
+
+	for {
+		for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+			i++
+		}
+		if i >= len(s) {
+			return attributes, true
+		}
+
+		switch s[i] {
+		case '.':
+			name := attributeName(s[i+1:])
+			if name == "" {
+				return nil, false
+			}
+			if class := attributes["class"]; class != "" {
+				attributes["class"] = class + " " + name
+			} else {
+				attributes["class"] = name
+			}
+			i += 1 + len(name)
+
+		case '#':
+			name := attributeName(s[i+1:])
+			if name == "" {
+				return nil, false
+			}
+			attributes["id"] = name
+			i += 1 + len(name)
+
+		default:
+			key := attributeName(s[i:])
+			if key == "" {
+				return nil, false
+			}
+			i += len(key)
+
+			if i >= len(s) || s[i] != '=' {
+				attributes[key] = ""
+				break
+			}
+			i++
+
+			if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+				end := strings.IndexByte(s[i+1:], s[i])
+				if end < 0 {
+					return nil, false
+				}
+				attributes[key] = s[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				value := s[i:]
+				if end := strings.IndexAny(value, " \t"); end >= 0 {
+					value = value[:end]
+				}
+				attributes[key] = value
+				i += len(value)
+			}
+		}
+
+		if i < len(s) && s[i] != ' ' && s[i] != '\t' {
+			return nil, false
+		}
+	}
+}
+
+// attributeName returns the leading run of characters allowed in attribute
+// names, ids and classes.
+func attributeName(s string) string {
+	end := 0
+	for end < len(s) {
+		c := s[end]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == ':' {
+			end++
+			continue
+		}
+		break
+	}
+	return s[:end]
+}
+
+// Attributes holds the HTML attributes given to a node by an attribute
+// list, "{#id .class key=value}". Nodes that can carry them embed it.
+type Attributes map[string]string
+
+func (a *Attributes) attributes() *Attributes {
+	return a
+}
+
+// attributed is implemented by the nodes that embed Attributes.
+type attributed interface {
+	attributes() *Attributes
+}
+
+// maxAttributesLength bounds the length of an attribute list, braces
+// included, so that an unclosed "{" is never scanned further than this.
+const maxAttributesLength = 1000
+
+// parseAttributes parses a kramdown or Pandoc style attribute list,
+// "{.class #id key=value}" or "{: .class}", at the start of s. It needs at
+// least one class, id or key=value pair, so that text such as "{name}" is
+// left alone. The list holds no other "{", even in quotes, and is at most
+// maxAttributesLength bytes long.
+func parseAttributes(s string) (Attributes, int, bool) {
+	if !strings.HasPrefix(s, "{") {
+		return nil, 0, false
+	}
+
+	end := -1
+	var quote byte
+	for i := 1; i < len(s) && i < maxAttributesLength && end < 0; i++ {
+		switch {
+		case s[i] == '\n' || s[i] == '{':
+			return nil, 0, false
+		case quote != 0:
+			if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '"' || s[i] == '\'':
+			quote = s[i]
+		case s[i] == '}':
+			end = i
+		}
+	}
+	if end < 0 {
+		return nil, 0, false
+	}
+
+	inner := strings.TrimPrefix(s[1:end], ":")
+	if !strings.ContainsAny(inner, ".#=") {
+		return nil, 0, false
+	}
+	attributes, ok := parseAttributeList(inner)
+	if !ok || len(attributes) == 0 {
+		return nil, 0, false
+	}
+	return Attributes(attributes), end + 1, true
+}
+
+// trailingAttributes parses an attribute list that ends text, separated from
+// what comes before it by whitespace, and returns where it starts. As a list
+// holds no "{", only the last one in text can start it.
+func (p *parser) trailingAttributes(text string) (Attributes, int, bool) {
+	if !p.opts.Attributes || !strings.HasSuffix(text, "}") {
+		return nil, 0, false
+	}
+	start := strings.LastIndexByte(text, '{')
+	if start < 0 || start > 0 && text[start-1] != ' ' && text[start-1] != '\t' {
+		return nil, 0, false
+	}
+	if attributes, n, ok := parseAttributes(text[start:]); ok && start+n == len(text) {
+		return attributes, start, true
+	}
+	return nil, 0, false
+}
+
+// isAttributeLine reports whether line is made only of an attribute list.
+// Such a line is never a lazy continuation line, so that after a list,
+// definition or block quote it applies to the container rather than to the
+// paragraph inside it.
+func (p *parser) isAttributeLine(line string) bool {
+	_, ok := p.parseAttributeLine(line)
+	return ok
+}
+
+// parseAttributeLine parses a line made only of an attribute list.
+func (p *parser) parseAttributeLine(line string) (Attributes, bool) {
+	if !p.opts.Attributes || indentWidth(line) > 3 {
+		return nil, false
+	}
+	line = strings.TrimSpace(line)
+	attributes, n, ok := parseAttributes(line)
+	if !ok || n != len(line) {
+		return nil, false
+	}
+	return attributes, true
+}
+
+// setAttributes adds attributes to those of node, appending classes to the
+// ones it already has. An id given to a heading becomes its ID.
+func setAttributes(node any, attributes Attributes) {
+	if heading, ok := node.(*Heading); ok {
+		if id, ok := attributes["id"]; ok {
+			heading.ID = id
+			delete(attributes, "id")
+		}
+	}
+
+	target, ok := node.(attributed)
+	if !ok {
+		return
+	}
+	current := target.attributes()
+	if *current == nil {
+		*current = make(Attributes, len(attributes))
+	}
+	for key, value := range attributes {
+		if class := (*current)["class"]; key == "class" && class != "" {
+			value = class + " " + value
+		}
+		(*current)[key] = value
+	}
+}
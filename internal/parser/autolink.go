@@ -0,0 +1,137 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	uriAutolink   = regexp.MustCompile(`^<([A-Za-z][A-Za-z0-9+.\-]{1,31}:[^<>\x00-\x20]*)>`)
+	emailAutolink = regexp.MustCompile("^<([a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*)>")
+)
+
+// parseAutolink parses an autolink at the start of text: an absolute URI or
+// an email address between pointy brackets. It returns the link and the
+// number of bytes consumed.
+func parseAutolink(text string) (*Link, int, bool) {
+	if match := uriAutolink.FindStringSubmatch(text); match != nil {
+		return &Link{
+			Text: []Inline{&Text{Content: match[1]}},
+			URL:  match[1],
+		}, len(match[0]), true
+	}
+	if match := emailAutolink.FindStringSubmatch(text); match != nil {
+		return &Link{
+			Text: []Inline{&Text{Content: match[1]}},
+			URL:  "mailto:" + match[1],
+		}, len(match[0]), true
+	}
+	return nil, 0, false
+}
+
+// linkify recognizes a GFM extended autolink, a bare "http://", "https://"
+// or "www." URL, at text[i]. It returns the link and the number of bytes
+// consumed.
+func linkify(text string, i int) (*Link, int, bool) {
+	rest := text[i:]
+	var prefix string
+	for _, p := range []string{"https://", "http://", "www."} {
+		if strings.HasPrefix(rest, p) {
+			prefix = p
+			break
+		}
+	}
+	if prefix == "" {
+		return nil, 0, false
+	}
+
+	// Extended autolinks start a line, follow whitespace, or follow one of
+	// the characters that may wrap them.
+	if i > 0 && !strings.ContainsRune(" \t\n*_~(", rune(text[i-1])) {
+		return nil, 0, false
+	}
+
+	end := strings.IndexAny(rest, " \t\n<")
+	if end < 0 {
+		end = len(rest)
+	}
+	url := trimAutolinkEnd(rest[:end])
+
+	domain := url
+	if prefix != "www." {
+		domain = domain[len(prefix):]
+	}
+	if j := strings.IndexAny(domain, ":/?#"); j >= 0 {
+		domain = domain[:j]
+	}
+	if !isValidDomain(domain) {
+		return nil, 0, false
+	}
+
+	href := url
+	if prefix == "www." {
+		href = "http://" + url
+	}
+	return &Link{
+		Text: []Inline{&Text{Content: url}},
+		URL:  href,
+	}, len(url), true
+}
+
+// trimAutolinkEnd drops the trailing punctuation that GFM leaves out of an
+// extended autolink: sentence punctuation, closing parentheses without a
+// matching opener and a trailing entity reference.
+func trimAutolinkEnd(url string) string {
+	unmatched := strings.Count(url, ")") - strings.Count(url, "(")
+	for url != "" {
+		switch last := url[len(url)-1]; {
+		case strings.IndexByte("?!.,:*_~", last) >= 0:
+			url = url[:len(url)-1]
+		case last == ')' && unmatched > 0:
+			url = url[:len(url)-1]
+			unmatched--
+		case last == ';' && entityStart(url) >= 0:
+			url = url[:entityStart(url)]
+		default:
+			return url
+		}
+	}
+	return url
+}
+
+// entityStart returns the index of the "&" of the entity reference, such as
+// "&amp;", that url ends with, or -1.
+func entityStart(url string) int {
+	i := len(url) - 2
+	for i >= 0 && (isASCIILetter(url[i]) || isDigit(url[i])) {
+		i--
+	}
+	if i < 0 || url[i] != '&' || i == len(url)-2 {
+		return -1
+	}
+	return i
+}
+
+// isValidDomain reports whether domain is made of at least two segments of
+// letters, digits, hyphens and underscores separated by periods, with no
+// underscores in the last two segments.
+func isValidDomain(domain string) bool {
+	segments := strings.Split(domain, ".")
+	if len(segments) < 2 {
+		return false
+	}
+	for i, segment := range segments {
+		if segment == "" {
+			return false
+		}
+		for _, c := range segment {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return false
+			}
+		}
+		if i >= len(segments)-2 && strings.Contains(segment, "_") {
+			return false
+		}
+	}
+	return true
+}
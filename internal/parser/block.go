@@ -5,31 +5,68 @@ type Block interface {
 }
 
 type Heading struct {
+	Attributes
 	Level int
 	Text  []Inline
+	// ID is the heading's anchor, set when heading IDs are enabled.
+	ID string
 }
 
 type Paragraph struct {
+	Attributes
 	Text []Inline
 }
 
 type List struct {
+	Attributes
 	Items []*ListItem
 	Type  ListType
+	// Start is the number of the first item of an ordered list.
+	Start int
+	// Delimiter is the bullet character ('-', '+' or '*') of an unordered
+	// list, or the '.' or ')' after the numbers of an ordered one.
+	Delimiter byte
+	// Tight is false when any of the items are separated by blank lines or
+	// contain blocks separated by blank lines. Paragraphs of tight lists are
+	// rendered without <p> tags.
+	Tight bool
 }
 
 type ListItem struct {
-	Level    int
-	Content  []Inline
-	Children *List
+	Attributes
+	Children []Block
+	// Checked is set for GFM task list items ("- [ ]" and "- [x]") and
+	// reports whether the task is done. It is nil for plain items.
+	Checked *bool
+}
+
+// ThematicBreak is a horizontal rule written as ---, *** or ___.
+type ThematicBreak struct {
+	Attributes
 }
 
 type CodeBlock struct {
+	Attributes
 	Lang string
 	Code string
+	// Info is the parsed info string of a fenced code block. It is empty
+	// for indented code blocks.
+	Info CodeInfo
+}
+
+// CodeInfo is the info string that follows the opening fence of a fenced
+// code block.
+type CodeInfo struct {
+	// Raw is the whole info string.
+	Raw string
+	// Language is the first word of the info string.
+	Language string
+	// Attributes holds the key=value pairs of a trailing {...} block.
+	Attributes map[string]string
 }
 
 type Table struct {
+	Attributes
 	Headers    []TableCell
 	Rows       [][]TableCell
 	Alignments []Alignment
@@ -40,13 +77,70 @@ type TableCell struct {
 }
 
 type Blockquote struct {
-	Items []*BlockquoteItem
+	Attributes
+	Children []Block
+}
+
+// Admonition is a callout box: a GitHub alert, "> [!WARNING]", or a fenced
+// container, ":::warning". Kind is lower case; Title is empty unless one
+// was given after the marker.
+type Admonition struct {
+	Attributes
+	Kind     string
+	Title    string
+	Children []Block
+}
+
+// FootnoteDefinition is the body of a footnote: "[^label]: text". Number
+// is its position in the order footnotes are first referenced, and
+// References the number of references to it.
+type FootnoteDefinition struct {
+	Label      string
+	Number     int
+	References int
+	Children   []Block
+}
+
+// DefinitionList is a list of terms and their definitions. Items holds
+// DefinitionTerm and DefinitionDescription blocks in document order: one
+// or more terms followed by one or more definitions, repeated.
+type DefinitionList struct {
+	Attributes
+	Items []Block
+}
+
+// DefinitionTerm is a term of a definition list, one per line.
+type DefinitionTerm struct {
+	Attributes
+	Text []Inline
+}
+
+// DefinitionDescription is a definition: ": text", continued by lines
+// indented four columns. Tight definitions render their paragraphs without
+// <p> tags.
+type DefinitionDescription struct {
+	Attributes
+	Children []Block
+	Tight    bool
+}
+
+// MathBlock is a LaTeX formula on lines of its own, between "$$" lines or
+// in a "math" code fence.
+type MathBlock struct {
+	Attributes
+	Content string
+}
+
+// TOCPlaceholder marks where the table of contents goes: a "[TOC]"
+// paragraph or a "<!-- toc -->" comment.
+type TOCPlaceholder struct {
+	Attributes
 }
 
-type BlockquoteItem struct {
-	Level    int
-	Content  []Inline
-	Children *Blockquote
+// HTMLBlock is a block of raw HTML, kept verbatim including its final line
+// ending.
+type HTMLBlock struct {
+	Content string
 }
 
 type Alignment int
@@ -65,10 +159,19 @@ const (
 	OrderedList
 )
 
-func (h Heading) isBlock()    {}
-func (p Paragraph) isBlock()  {}
-func (l List) isBlock()       {}
-func (li ListItem) isBlock()  {}
-func (c CodeBlock) isBlock()  {}
-func (t Table) isBlock()      {}
-func (b Blockquote) isBlock() {}
+func (h Heading) isBlock()               {}
+func (p Paragraph) isBlock()             {}
+func (l List) isBlock()                  {}
+func (li ListItem) isBlock()             {}
+func (c CodeBlock) isBlock()             {}
+func (t ThematicBreak) isBlock()         {}
+func (t Table) isBlock()                 {}
+func (b Blockquote) isBlock()            {}
+func (a Admonition) isBlock()            {}
+func (m MathBlock) isBlock()             {}
+func (d DefinitionList) isBlock()        {}
+func (d DefinitionTerm) isBlock()        {}
+func (d DefinitionDescription) isBlock() {}
+func (t TOCPlaceholder) isBlock()        {}
+func (h HTMLBlock) isBlock()             {}
+func (f FootnoteDefinition) isBlock()    {}
@@ -0,0 +1,65 @@
+package parser
+
+import "strings"
+
+// stripBlockquoteMarker removes the '>' marker, indented by at most three
+// spaces, and the optional space after it from line.
+func stripBlockquoteMarker(line string) (string, bool) {
+	indent := indentWidth(line)
+	if indent > 3 {
+		return "", false
+	}
+	trimmedLine := strings.TrimLeft(line, " \t")
+	if !strings.HasPrefix(trimmedLine, ">") {
+		return "", false
+	}
+
+	// A tab after the marker counts as the optional space; the columns
+	// left over from it still belong to the content.
+	rest := expandIndent(trimmedLine[1:], indent+1)
+	return strings.TrimPrefix(rest, " "), true
+}
+
+// parseBlockquote parses a block quote, or a GitHub alert when its first
+// line is an alert marker.
+func (p *parser) parseBlockquote() (Block, error) {
+	content, _ := stripBlockquoteMarker(p.line)
+	lines := []string{content}
+	lazy := make(map[int]bool)
+
+	paragraph := lazyTracker{}
+	paragraph.add(p, content)
+
+	for {
+		next, ok := p.peekLine()
+		if !ok {
+			break
+		}
+
+		if content, isQuoted := stripBlockquoteMarker(next); isQuoted {
+			p.readLine()
+			lines = append(lines, content)
+			paragraph.add(p, content)
+		} else if paragraph.open && !p.interruptsParagraph(next) && !p.isListMarker(next) && !p.isAttributeLine(next) {
+			// Lazy continuation line of the quote's open paragraph.
+			p.readLine()
+			lazy[len(lines)] = true
+			lines = append(lines, next)
+		} else {
+			break
+		}
+	}
+
+	if alert, ok, err := p.parseAlert(lines, lazy); ok || err != nil {
+		return alert, err
+	}
+
+	children, _, err := p.parseContainer(lines, lazy)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Blockquote{
+		Children: children,
+	}, nil
+}
@@ -0,0 +1,155 @@
+package parser
+
+import (
+	"strings"
+)
+
+// codeFence is the opening fence of a fenced code block.
+type codeFence struct {
+	char   byte
+	length int
+	indent int
+	info   string
+}
+
+// parseCodeFence recognises a line of three or more backticks or tildes,
+// indented by at most three spaces and followed by an optional info string.
+func parseCodeFence(line string) (codeFence, bool) {
+	indent := indentWidth(line)
+	if indent > 3 {
+		return codeFence{}, false
+	}
+	trimmedLine := strings.TrimLeft(line, " \t")
+	if trimmedLine == "" || (trimmedLine[0] != '`' && trimmedLine[0] != '~') {
+		return codeFence{}, false
+	}
+
+	fence := codeFence{
+		char:   trimmedLine[0],
+		indent: indent,
+	}
+	for fence.length < len(trimmedLine) && trimmedLine[fence.length] == fence.char {
+		fence.length++
+	}
+	if fence.length < 3 {
+		return codeFence{}, false
+	}
+
+	fence.info = strings.TrimSpace(trimmedLine[fence.length:])
+	// The info string of a backtick fence cannot contain backticks, or
+	// inline code spans would be mistaken for fences.
+	if fence.char == '`' && strings.IndexByte(fence.info, '`') >= 0 {
+		return codeFence{}, false
+	}
+	return fence, true
+}
+
+func isCodeFence(line string) bool {
+	_, ok := parseCodeFence(line)
+	return ok
+}
+
+// closedBy reports whether line is a closing fence for f: the same character,
+// at least as long, indented by at most three spaces and followed only by
+// whitespace.
+func (f codeFence) closedBy(line string) bool {
+	if indentWidth(line) > 3 {
+		return false
+	}
+	trimmedLine := strings.TrimSpace(line)
+	if len(trimmedLine) < f.length {
+		return false
+	}
+	return strings.Trim(trimmedLine, string(f.char)) == ""
+}
+
+// parseCodeBlock parses a fenced code block. With math enabled, a "math"
+// fence holds a MathBlock instead.
+func (p *parser) parseCodeBlock() (Block, error) {
+	fence, _ := parseCodeFence(p.line)
+	var code strings.Builder
+
+	// An unclosed fence runs to the end of its container.
+	for p.pos < len(p.input) {
+		p.readLine()
+		if fence.closedBy(p.line) {
+			break
+		}
+		code.WriteString(stripColumns(p.line, fence.indent))
+		code.WriteByte('\n')
+	}
+
+	info := parseCodeInfo(unescapeString(fence.info))
+	if p.opts.Math && info.Language == "math" {
+		return &MathBlock{Content: strings.TrimSpace(code.String())}, nil
+	}
+
+	return &CodeBlock{
+		Lang: info.Language,
+		Code: code.String(),
+		Info: info,
+	}, nil
+}
+
+// parseIndentedCodeBlock parses a code block made of lines indented by four
+// or more columns. Blank lines inside the block are kept, trailing ones are
+// not.
+func (p *parser) parseIndentedCodeBlock() (*CodeBlock, error) {
+	lines := []string{stripColumns(p.line, 4)}
+	mark := p.pos
+	blanks := 0
+
+	for {
+		next, ok := p.peekLine()
+		if !ok {
+			break
+		}
+
+		if isBlankLine(next) {
+			p.readLine()
+			lines = append(lines, stripColumns(next, 4))
+			blanks++
+			continue
+		}
+
+		if indentWidth(next) < 4 {
+			break
+		}
+
+		p.readLine()
+		lines = append(lines, stripColumns(next, 4))
+		mark = p.pos
+		blanks = 0
+	}
+
+	p.pos = mark
+	lines = lines[:len(lines)-blanks]
+
+	return &CodeBlock{
+		Code: strings.Join(lines, "\n") + "\n",
+	}, nil
+}
+
+// parseCodeInfo splits the info string of a fenced code block into the
+// language, which is its first word, and the attributes of a trailing
+// {key=value ...} block, as in:
+//
+//	```go {linenos=true hl_lines="3-5"}
+func parseCodeInfo(raw string) CodeInfo {
+	info := CodeInfo{
+		Raw: raw,
+	}
+
+	words := raw
+	if open := strings.IndexByte(raw, '{'); open >= 0 && strings.HasSuffix(raw, "}") {
+		if attributes, ok := parseAttributeList(raw[open+1 : len(raw)-1]); ok {
+			info.Attributes = attributes
+			words = raw[:open]
+		}
+	}
+
+	if fields := strings.Fields(words); len(fields) > 0 {
+		info.Language = fields[0]
+	}
+	return info
+}
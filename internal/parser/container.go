@@ -0,0 +1,143 @@
+package parser
+
+import "strings"
+
+// parseContainer parses the content lines of a container block, with the
+// container's own markers and indentation already removed. lazy holds the
+// indexes of the lines that were lazy continuation lines.
+func (p *parser) parseContainer(lines []string, lazy map[int]bool) ([]Block, bool, error) {
+	lazyLines := make(map[int]bool, len(lazy))
+	offset := 0
+	for i, line := range lines {
+		if lazy[i] {
+			lazyLines[offset] = true
+		}
+		offset += len(line) + 1
+	}
+
+	child := &parser{
+		input:       strings.Join(lines, "\n") + "\n",
+		opts:        p.opts,
+		lazyLines:   lazyLines,
+		references:  p.references,
+		footnotes:   p.footnotes,
+		headingIDs:  p.headingIDs,
+		skipInlines: p.skipInlines,
+		depth:       p.depth + 1,
+	}
+	blocks, err := child.parseBlocks()
+	return blocks, child.blankBetweenBlocks, err
+}
+
+// lazyTracker follows the lines of a container block to know whether its
+// content currently ends in an open paragraph, the only block a lazy
+// continuation line can continue.
+type lazyTracker struct {
+	open  bool
+	fence *codeFence
+	html  htmlBlockKind
+}
+
+func (t *lazyTracker) add(p *parser, line string) {
+	// The markers of nested block quotes and list items are peeled off in
+	// turn, no deeper than the parser nests containers.
+	for depth := p.depth; ; depth++ {
+		if depth >= maxContainerDepth {
+			t.open = true
+			return
+		}
+		content, nested := t.addLine(p, line)
+		if !nested {
+			return
+		}
+		line = content
+	}
+}
+
+// addLine follows a single line. When the line opens a block quote or a list
+// item, it returns the container's content, which still has to be followed.
+func (t *lazyTracker) addLine(p *parser, line string) (string, bool) {
+	trimmedLine := strings.TrimLeft(line, " \t")
+	html := htmlBlockStart(line)
+
+	switch {
+	case t.fence != nil:
+		if t.fence.closedBy(line) {
+			t.fence = nil
+		}
+	case t.html != htmlBlockNone:
+		if t.html.endsAtBlankLine() && isBlankLine(line) || t.html.endsBy(line) {
+			t.html = htmlBlockNone
+		}
+	case isBlankLine(line):
+		t.open = false
+	case !t.open && indentWidth(line) > 3:
+		// Indented code.
+	case isCodeFence(line):
+		fence, _ := parseCodeFence(line)
+		t.fence = &fence
+		t.open = false
+	case atxHeadingLevel(line) > 0, isThematicBreak(line), p.isAdmonitionFence(line), p.isMathBlockStart(line):
+		t.open = false
+	case html != htmlBlockNone && (!t.open || html != htmlBlockOther):
+		t.open = false
+		if !html.endsBy(line) {
+			t.html = html
+		}
+	case strings.HasPrefix(trimmedLine, ">"):
+		return trimmedLine[1:], true
+	default:
+		if marker, ok := p.parseListMarker(line); ok {
+			t.open = false
+			return marker.content, true
+		}
+		t.open = true
+	}
+	return "", false
+}
+
+// expandIndent replaces the tabs in the leading whitespace of s with the
+// spaces they stand for, given that s starts at the given column. Container
+// blocks expand the indentation of their content this way so that tab stops
+// keep lining up once the container's prefix is removed.
+func expandIndent(s string, column int) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case ' ':
+			b.WriteByte(' ')
+			column++
+		case '\t':
+			width := 4 - column%4
+			b.WriteString(strings.Repeat(" ", width))
+			column += width
+		default:
+			return b.String() + s[i:]
+		}
+	}
+	return b.String()
+}
+
+// stripColumns removes up to n columns of leading whitespace from line. A tab
+// that is only partly consumed is replaced by the spaces that remain of it.
+func stripColumns(line string, n int) string {
+	column := 0
+	for i := 0; i < len(line); i++ {
+		if column >= n {
+			return line[i:]
+		}
+		switch line[i] {
+		case ' ':
+			column++
+		case '\t':
+			width := 4 - column%4
+			if column+width > n {
+				return strings.Repeat(" ", column+width-n) + line[i+1:]
+			}
+			column += width
+		default:
+			return line[i:]
+		}
+	}
+	return ""
+}
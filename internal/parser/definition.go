@@ -0,0 +1,116 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// definitionMarker matches the start of a definition: a colon indented by at
+// most three spaces and followed by whitespace and the definition.
+var definitionMarker = regexp.MustCompile(`^ {0,3}:[ \t]+\S`)
+
+// definitionContinuationWidth is how far lines after the first must be
+// indented to continue a definition, as with PHP Markdown Extra.
+const definitionContinuationWidth = 4
+
+func (p *parser) isDefinitionMarker(line string) bool {
+	return p.opts.DefinitionLists && definitionMarker.MatchString(line)
+}
+
+// startsDefinitionList reports whether the current line is the first term of
+// a definition list: a run of term lines followed, after at most one blank
+// line, by a definition. Nothing is consumed.
+func (p *parser) startsDefinitionList() bool {
+	if !p.opts.DefinitionLists || p.isDefinitionMarker(p.line) ||
+		p.interruptsParagraph(p.line) || p.isListMarker(p.line) {
+		return false
+	}
+
+	currentPos := p.pos
+	currentLine := p.line
+	defer func() {
+		p.pos = currentPos
+		p.line = currentLine
+	}()
+
+	next, ok := p.peekLine()
+	for ok && !isBlankLine(next) && !p.interruptsParagraph(next) {
+		p.readLine()
+		next, ok = p.peekLine()
+	}
+	if ok && isBlankLine(next) {
+		p.readLine()
+		next, ok = p.peekLine()
+	}
+	return ok && p.isDefinitionMarker(next)
+}
+
+// parseDefinitionList parses groups of terms, one per line, each followed by
+// one or more definitions. A definition preceded by a blank line, or whose
+// content holds one, has its paragraphs wrapped in <p> tags.
+func (p *parser) parseDefinitionList() (*DefinitionList, error) {
+	list := &DefinitionList{}
+
+	for {
+		list.Items = append(list.Items, &DefinitionTerm{Text: p.parseInline(strings.TrimSpace(p.line))})
+		next, _ := p.peekLine()
+		for !isBlankLine(next) && !p.interruptsParagraph(next) {
+			p.readLine()
+			list.Items = append(list.Items, &DefinitionTerm{Text: p.parseInline(strings.TrimSpace(p.line))})
+			next, _ = p.peekLine()
+		}
+
+		for {
+			// Past the terms or the previous definition, and the blank
+			// lines after them, to the next definition, if any.
+			mark := p.pos
+			blanks := 0
+			next, ok := p.peekLine()
+			for ok && isBlankLine(next) {
+				p.readLine()
+				blanks++
+				next, ok = p.peekLine()
+			}
+			if !ok || !p.isDefinitionMarker(next) {
+				p.pos = mark
+				break
+			}
+			p.readLine()
+
+			content := strings.TrimLeft(strings.TrimLeft(p.line, " \t")[1:], " \t")
+			lines, lazy := p.collectListItemLines(listMarker{
+				width:   definitionContinuationWidth,
+				content: content,
+			})
+			children, blankBetweenBlocks, err := p.parseContainer(lines, lazy)
+			if err != nil {
+				return nil, err
+			}
+			list.Items = append(list.Items, &DefinitionDescription{
+				Children: children,
+				Tight:    blanks == 0 && !blankBetweenBlocks,
+			})
+		}
+
+		// Past blank lines to the terms of the next group, if any.
+		mark := p.pos
+		currentLine := p.line
+		next, ok := p.peekLine()
+		for ok && isBlankLine(next) {
+			p.readLine()
+			next, ok = p.peekLine()
+		}
+		if !ok {
+			p.pos = mark
+			break
+		}
+		p.readLine()
+		if !p.startsDefinitionList() {
+			p.pos = mark
+			p.line = currentLine
+			break
+		}
+	}
+
+	return list, nil
+}
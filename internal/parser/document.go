@@ -0,0 +1,54 @@
+package parser
+
+type Document struct {
+	// Meta holds the document's front matter, or nil when it has none.
+	Meta   map[string]any
+	Blocks []Block
+	// References holds the document's link reference definitions, keyed
+	// by normalized label.
+	References map[string]*LinkReference
+	// Footnotes holds the referenced footnote definitions in the order of
+	// their first reference.
+	Footnotes []*FootnoteDefinition
+}
+
+// TaskCounts returns the number of open and completed task list items in
+// the document, including those nested in other blocks.
+func (d *Document) TaskCounts() (open, done int) {
+	walkBlocks(d.Blocks, func(block Block) {
+		item, ok := block.(*ListItem)
+		if !ok || item.Checked == nil {
+			return
+		}
+		if *item.Checked {
+			done++
+		} else {
+			open++
+		}
+	})
+	return open, done
+}
+
+// walkBlocks calls fn for every block in blocks and, depth first, for the
+// blocks nested inside them, list items included.
+func walkBlocks(blocks []Block, fn func(Block)) {
+	for _, block := range blocks {
+		fn(block)
+
+		switch b := block.(type) {
+		case *List:
+			for _, item := range b.Items {
+				fn(item)
+				walkBlocks(item.Children, fn)
+			}
+		case *Blockquote:
+			walkBlocks(b.Children, fn)
+		case *Admonition:
+			walkBlocks(b.Children, fn)
+		case *DefinitionList:
+			walkBlocks(b.Items, fn)
+		case *DefinitionDescription:
+			walkBlocks(b.Children, fn)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package parser
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// delimiter is a run of '*' or '_' characters that may open or close
+// emphasis, or of '~', '=' or '^' for the inline extensions. Its text stays
+// in the inline list as a Text node until the run is matched.
+type delimiter struct {
+	node      *Text
+	char      byte
+	count     int
+	origCount int
+	canOpen   bool
+	canClose  bool
+	// id orders delimiters by position, and survives removals from the
+	// delimiter stack.
+	id int
+	// prev and next link the delimiters still on the stack while
+	// processEmphasis matches them.
+	prev, next *delimiter
+}
+
+// scanDelimiterRun measures the run of delimiter characters at text[i] and
+// works out from its neighbours whether it can open or close emphasis.
+func scanDelimiterRun(text string, i int) *delimiter {
+	char := text[i]
+	n := 0
+	for i+n < len(text) && text[i+n] == char {
+		n++
+	}
+
+	// The start and end of the text count as whitespace.
+	before, after := ' ', ' '
+	if i > 0 {
+		before, _ = utf8.DecodeLastRuneInString(text[:i])
+	}
+	if i+n < len(text) {
+		after, _ = utf8.DecodeRuneInString(text[i+n:])
+	}
+
+	beforeSpace, afterSpace := unicode.IsSpace(before), unicode.IsSpace(after)
+	beforePunct, afterPunct := isPunctuation(before), isPunctuation(after)
+
+	leftFlanking := !afterSpace && (!afterPunct || beforeSpace || beforePunct)
+	rightFlanking := !beforeSpace && (!beforePunct || afterSpace || afterPunct)
+
+	d := &delimiter{
+		node:      &Text{Content: text[i : i+n]},
+		char:      char,
+		count:     n,
+		origCount: n,
+	}
+	if char == '_' {
+		// Underscores do not emphasize inside words.
+		d.canOpen = leftFlanking && (!rightFlanking || beforePunct)
+		d.canClose = rightFlanking && (!leftFlanking || afterPunct)
+	} else {
+		d.canOpen = leftFlanking
+		d.canClose = rightFlanking
+	}
+	return d
+}
+
+// isDelimiter reports whether d can take part in matching. Emphasis runs
+// always can; the extension runs only when their extension is enabled and
+// the run has the length it uses: "~~" for strikethrough, "==" for
+// highlight, "~" for subscript and "^" for superscript.
+func (p *parser) isDelimiter(d *delimiter) bool {
+	switch d.char {
+	case '*', '_':
+		return true
+	case '~':
+		return d.count == 2 && p.opts.Strikethrough || d.count == 1 && p.opts.Subscript
+	case '=':
+		return d.count == 2 && p.opts.Highlight
+	case '^':
+		return d.count == 1 && p.opts.Superscript
+	}
+	return false
+}
+
+// isPunctuation reports whether r is a Unicode punctuation character in the
+// CommonMark sense, which includes symbols.
+func isPunctuation(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+// openersBottomKey indexes the lower bound of the opener search. Closers
+// that differ in character, in whether they can open, or in their length
+// modulo 3 may find different openers.
+type openersBottomKey struct {
+	char    byte
+	canOpen bool
+	mod     int
+}
+
+// emphasisMatch is a matched pair of delimiter runs, each giving up use
+// characters to wrap the inlines between them.
+type emphasisMatch struct {
+	opener, closer *delimiter
+	use            int
+}
+
+// processEmphasis matches the delimiters of inlines against each other,
+// replacing the inlines between each matched pair with Italic or Bold
+// nodes, or with the node of an inline extension. Unmatched delimiters stay
+// as literal text.
+func processEmphasis(inlines []Inline, delimiters []*delimiter) []Inline {
+	// The delimiters are linked so that removing one from the stack takes
+	// constant time.
+	for i, d := range delimiters {
+		d.prev, d.next = nil, nil
+		if i > 0 {
+			d.prev = delimiters[i-1]
+			delimiters[i-1].next = d
+		}
+	}
+
+	openersBottom := make(map[openersBottomKey]int)
+	var matches []emphasisMatch
+
+	var closer *delimiter
+	if len(delimiters) > 0 {
+		closer = delimiters[0]
+	}
+	for closer != nil {
+		if !closer.canClose {
+			closer = closer.next
+			continue
+		}
+
+		key := openersBottomKey{closer.char, closer.canOpen, closer.origCount % 3}
+		bottom, ok := openersBottom[key]
+		if !ok {
+			bottom = -1
+		}
+
+		opener := closer.prev
+		for opener != nil && opener.id > bottom && !opensFor(opener, closer) {
+			opener = opener.prev
+		}
+
+		if opener == nil || opener.id <= bottom {
+			openersBottom[key] = closer.id - 1
+			next := closer.next
+			if !closer.canOpen {
+				unlinkDelimiter(closer)
+			}
+			closer = next
+			continue
+		}
+
+		use := 1
+		if opener.count >= 2 && closer.count >= 2 {
+			use = 2
+		}
+		if !isEmphasisChar(closer.char) {
+			use = closer.count
+		}
+		opener.count -= use
+		closer.count -= use
+		opener.node.Content = opener.node.Content[:opener.count]
+		closer.node.Content = closer.node.Content[:closer.count]
+		matches = append(matches, emphasisMatch{opener: opener, closer: closer, use: use})
+
+		// Delimiters inside the emphasis can no longer match.
+		opener.next = closer
+		closer.prev = opener
+
+		if opener.count == 0 {
+			unlinkDelimiter(opener)
+		}
+		if closer.count == 0 {
+			next := closer.next
+			unlinkDelimiter(closer)
+			closer = next
+		}
+	}
+
+	return mergeTexts(collapseBoldItalic(wrapEmphasis(inlines, matches)))
+}
+
+func unlinkDelimiter(d *delimiter) {
+	if d.prev != nil {
+		d.prev.next = d.next
+	}
+	if d.next != nil {
+		d.next.prev = d.prev
+	}
+}
+
+// wrapEmphasis builds the emphasis nodes of matches, in the order they were
+// matched, around the inlines between their runs. Matches never cross, so
+// the nodes nest: an emphasis opens just after its opener's run, outside
+// any matched earlier from the same run, and closes just before its
+// closer's run, outside any matched earlier by it. Runs used up entirely
+// are dropped.
+func wrapEmphasis(inlines []Inline, matches []emphasisMatch) []Inline {
+	if len(matches) == 0 {
+		return inlines
+	}
+
+	positions := make(map[*Text]int)
+	for i, inline := range inlines {
+		if t, ok := inline.(*Text); ok {
+			positions[t] = i
+		}
+	}
+	opens := make(map[int][]int)
+	closes := make(map[int][]int)
+	for m, match := range matches {
+		opens[positions[match.opener.node]] = append(opens[positions[match.opener.node]], m)
+		closes[positions[match.closer.node]] = append(closes[positions[match.closer.node]], m)
+	}
+
+	// Each open emphasis collects its content on the stack, above the
+	// inlines around it.
+	stack := [][]Inline{nil}
+	for i, inline := range inlines {
+		for _, m := range closes[i] {
+			content := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			emphasis := newEmphasis(matches[m].closer.char, matches[m].use, content)
+			stack[len(stack)-1] = append(stack[len(stack)-1], emphasis)
+		}
+		if t, ok := inline.(*Text); !ok || t.Content != "" {
+			stack[len(stack)-1] = append(stack[len(stack)-1], inline)
+		}
+		for range opens[i] {
+			stack = append(stack, nil)
+		}
+	}
+	return stack[0]
+}
+
+// newEmphasis wraps content in the node for a matched pair of runs of char,
+// each use characters long.
+func newEmphasis(char byte, use int, content []Inline) Inline {
+	switch {
+	case char == '~' && use == 2:
+		return &Strikethrough{Content: content}
+	case char == '~':
+		return &Subscript{Content: content}
+	case char == '=':
+		return &Highlight{Content: content}
+	case char == '^':
+		return &Superscript{Content: content}
+	case use == 2:
+		return &Bold{Content: content}
+	}
+	return &Italic{Content: content}
+}
+
+func isEmphasisChar(char byte) bool {
+	return char == '*' || char == '_'
+}
+
+// opensFor reports whether opener can be closed by closer. Extension runs
+// only close runs of the same length. For emphasis, when either run can
+// both open and close, the rule of 3 rejects pairs whose combined length is
+// a multiple of 3 unless both lengths are.
+func opensFor(opener, closer *delimiter) bool {
+	if opener.char != closer.char || !opener.canOpen {
+		return false
+	}
+	if !isEmphasisChar(closer.char) {
+		return opener.count == closer.count
+	}
+	if (opener.canClose || closer.canOpen) &&
+		(opener.origCount+closer.origCount)%3 == 0 &&
+		(opener.origCount%3 != 0 || closer.origCount%3 != 0) {
+		return false
+	}
+	return true
+}
+
+// collapseBoldItalic turns emphasis wrapping nothing but strong emphasis,
+// as produced by "***text***", into a single BoldItalic node.
+func collapseBoldItalic(inlines []Inline) []Inline {
+	for i, inline := range inlines {
+		italic, ok := inline.(*Italic)
+		if !ok || len(italic.Content) != 1 {
+			continue
+		}
+		if bold, ok := italic.Content[0].(*Bold); ok {
+			inlines[i] = &BoldItalic{Content: bold.Content}
+		}
+	}
+	return inlines
+}
+
+// mergeTexts joins adjacent Text nodes, such as the leftovers of unmatched
+// delimiter runs and the text around them.
+func mergeTexts(inlines []Inline) []Inline {
+	merged := inlines[:0]
+	var b strings.Builder
+	var pending *Text
+
+	flush := func() {
+		if pending != nil {
+			pending.Content = b.String()
+			if pending.Content != "" {
+				merged = append(merged, pending)
+			}
+			pending = nil
+			b.Reset()
+		}
+	}
+
+	for _, inline := range inlines {
+		if t, ok := inline.(*Text); ok {
+			if pending == nil {
+				pending = t
+			}
+			b.WriteString(t.Content)
+			continue
+		}
+		flush()
+		merged = append(merged, inline)
+	}
+	flush()
+
+	return merged
+}
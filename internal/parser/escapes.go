@@ -0,0 +1,76 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// parseEntity parses an entity or numeric character reference at the start
+// of text: "&copy;", "&#169;" or "&#xA9;". It returns the decoded text and
+// the number of bytes consumed.
+func parseEntity(text string) (string, int, bool) {
+	if len(text) < 3 || text[0] != '&' {
+		return "", 0, false
+	}
+	end := strings.IndexByte(text, ';')
+	if end < 0 {
+		return "", 0, false
+	}
+	name := text[1:end]
+
+	if !strings.HasPrefix(name, "#") {
+		if decoded, ok := entities[name]; ok {
+			return decoded, end + 1, true
+		}
+		return "", 0, false
+	}
+
+	digits, base, maxDigits := name[1:], 10, 7
+	if strings.HasPrefix(digits, "x") || strings.HasPrefix(digits, "X") {
+		digits, base, maxDigits = digits[1:], 16, 6
+	}
+	if digits == "" || len(digits) > maxDigits || strings.ContainsAny(digits, "+-") {
+		return "", 0, false
+	}
+	code, err := strconv.ParseUint(digits, base, 32)
+	if err != nil {
+		return "", 0, false
+	}
+
+	// NUL, surrogates and code points past the Unicode range all become the
+	// replacement character.
+	r := rune(code)
+	if r == 0 || !utf8.ValidRune(r) {
+		r = utf8.RuneError
+	}
+	return string(r), end + 1, true
+}
+
+// unescapeString resolves backslash escapes and entity references in
+// text that is not parsed for inlines, such as link destinations, link
+// titles and code block info strings.
+func unescapeString(s string) string {
+	if !strings.ContainsAny(s, "\\&") {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && isASCIIPunctuation(s[i+1]):
+			b.WriteByte(s[i+1])
+			i++
+		case s[i] == '&':
+			if decoded, n, ok := parseEntity(s[i:]); ok {
+				b.WriteString(decoded)
+				i += n - 1
+			} else {
+				b.WriteByte('&')
+			}
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,118 @@
+package parser
+
+import "regexp"
+
+// Footnote labels, like link labels, hold no brackets and at most 999
+// characters, so that a run of "[^" is not rescanned from each one.
+var (
+	footnoteDefinitionStart = regexp.MustCompile(`^ {0,3}\[\^([^\[\]\s]{1,999})\]:[ \t]*`)
+	footnoteReference       = regexp.MustCompile(`^\[\^([^\[\]\s]{1,999})\]`)
+)
+
+// footnoteContinuationWidth is how far the continuation lines of a footnote
+// definition are indented.
+const footnoteContinuationWidth = 4
+
+// footnoteIndex tracks the footnotes of a document across both parsing
+// passes and every child parser.
+type footnoteIndex struct {
+	// defined holds the normalized labels of every definition, collected
+	// by the first pass so references can precede their definitions.
+	defined map[string]bool
+	// definitions holds the parsed definitions; the first one of a label
+	// wins.
+	definitions map[string]*FootnoteDefinition
+	// order lists the labels in the order they are first referenced.
+	order []string
+	// numbers holds the number of each referenced label, its position in
+	// order counting from 1.
+	numbers map[string]int
+	// references counts the references to each label.
+	references map[string]int
+}
+
+func newFootnoteIndex() *footnoteIndex {
+	return &footnoteIndex{
+		defined:     make(map[string]bool),
+		definitions: make(map[string]*FootnoteDefinition),
+		numbers:     make(map[string]int),
+		references:  make(map[string]int),
+	}
+}
+
+// isFootnoteDefinition reports whether line opens a footnote definition.
+func (p *parser) isFootnoteDefinition(line string) bool {
+	return p.opts.Footnotes && footnoteDefinitionStart.MatchString(line)
+}
+
+// parseFootnoteDefinition parses a footnote definition: "[^label]: text"
+// followed by any lines indented by four columns, which may hold further
+// paragraphs and other blocks. The definition is recorded in the footnote
+// index and no block is returned in its place.
+func (p *parser) parseFootnoteDefinition() (Block, error) {
+	match := footnoteDefinitionStart.FindStringSubmatch(p.line)
+	label := match[1]
+	key := normalizeLabel(label)
+
+	// Indented lines continue the definition, just as they continue a
+	// list item whose content starts four columns in.
+	lines, lazy := p.collectListItemLines(listMarker{
+		width:   footnoteContinuationWidth,
+		content: p.line[len(match[0]):],
+	})
+	children, _, err := p.parseContainer(lines, lazy)
+	if err != nil {
+		return nil, err
+	}
+
+	p.footnotes.defined[key] = true
+	if _, ok := p.footnotes.definitions[key]; !ok && !p.skipInlines {
+		p.footnotes.definitions[key] = &FootnoteDefinition{
+			Label:    label,
+			Children: children,
+		}
+	}
+	return nil, nil
+}
+
+// parseFootnoteReference parses a reference to a defined footnote at the
+// start of text, numbering footnotes in the order they are first
+// referenced. It returns the node and the number of bytes consumed.
+func (p *parser) parseFootnoteReference(text string) (*FootnoteReference, int, bool) {
+	match := footnoteReference.FindStringSubmatch(text)
+	if match == nil {
+		return nil, 0, false
+	}
+	key := normalizeLabel(match[1])
+	if !p.footnotes.defined[key] {
+		return nil, 0, false
+	}
+
+	if p.footnotes.references[key] == 0 {
+		p.footnotes.order = append(p.footnotes.order, key)
+		p.footnotes.numbers[key] = len(p.footnotes.order)
+	}
+	p.footnotes.references[key]++
+
+	return &FootnoteReference{
+		Label:  match[1],
+		Number: p.footnotes.numbers[key],
+		Index:  p.footnotes.references[key],
+	}, len(match[0]), true
+}
+
+// referencedFootnotes returns the definitions that are referenced at least
+// once, numbered in the order of their first reference.
+func (f *footnoteIndex) referencedFootnotes() []*FootnoteDefinition {
+	var footnotes []*FootnoteDefinition
+	for _, key := range f.order {
+		definition, ok := f.definitions[key]
+		if !ok {
+			continue
+		}
+		definition.Number = f.numbers[key]
+		definition.References = f.references[key]
+		footnotes = append(footnotes, definition)
+	}
+	return footnotes
+}
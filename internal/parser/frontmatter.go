@@ -0,0 +1,253 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// extractFrontMatter splits the front matter off the start of markdown and
+// parses it. YAML front matter sits between "---" lines (or "---" and
+// "..."), TOML front matter between "+++" lines, and JSON front matter is a
+// single object that opens the document. Markdown without front matter, or
+// whose would-be front matter does not parse, is returned unchanged with nil
+// metadata: a document may well open with a thematic break.
+func extractFrontMatter(markdown string) (map[string]any, string) {
+	firstLine, rest, _ := strings.Cut(markdown, "\n")
+
+	var meta map[string]any
+	var remaining string
+	var err error
+	switch strings.TrimRight(firstLine, " \t") {
+	case "---":
+		body, after, ok := cutFrontMatter(rest, "---", "...")
+		if !ok {
+			return nil, markdown
+		}
+		meta, err = parseYAML(body)
+		remaining = after
+
+	case "+++":
+		body, after, ok := cutFrontMatter(rest, "+++")
+		if !ok {
+			return nil, markdown
+		}
+		meta, err = parseTOML(body)
+		remaining = after
+
+	default:
+		if strings.HasPrefix(markdown, "{") {
+			if meta, remaining, ok := cutJSONFrontMatter(markdown); ok {
+				return meta, remaining
+			}
+		}
+		return nil, markdown
+	}
+
+	if err != nil {
+		return nil, markdown
+	}
+	return meta, remaining
+}
+
+// cutFrontMatter finds the first line of text that is one of the closing
+// delimiters and returns the lines before it and the text after it.
+func cutFrontMatter(text string, closers ...string) (string, string, bool) {
+	offset := 0
+	for offset < len(text) {
+		line, _, _ := strings.Cut(text[offset:], "\n")
+		next := offset + len(line) + 1
+		for _, closer := range closers {
+			if strings.TrimRight(line, " \t") == closer {
+				return text[:offset], text[min(next, len(text)):], true
+			}
+		}
+		offset = next
+	}
+	return "", "", false
+}
+
+// cutJSONFrontMatter decodes the JSON object that opens text. Nothing but
+// whitespace may follow the object on its last line.
+func cutJSONFrontMatter(text string) (map[string]any, string, bool) {
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+
+	var meta map[string]any
+	if err := decoder.Decode(&meta); err != nil {
+		return nil, "", false
+	}
+	end := int(decoder.InputOffset())
+	line, remaining, _ := strings.Cut(text[end:], "\n")
+	if strings.TrimSpace(line) != "" {
+		return nil, "", false
+	}
+
+	for key, value := range meta {
+		meta[key] = convertJSONNumbers(value)
+	}
+	return meta, remaining, true
+}
+
+// convertJSONNumbers turns the json.Numbers in a decoded value into ints
+// where they are integral and float64s otherwise, as the YAML and TOML
+// parsers do.
+func convertJSONNumbers(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := strconv.Atoi(v.String()); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for key, item := range v {
+			v[key] = convertJSONNumbers(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = convertJSONNumbers(item)
+		}
+	}
+	return value
+}
+
+var (
+	integerLiteral = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	floatLiteral   = regexp.MustCompile(`^[-+]?(?:[0-9]+\.?[0-9]*|\.[0-9]+)(?:[eE][-+]?[0-9]+)?$`)
+)
+
+// dateLayouts are the date and time formats front matter values are
+// recognised in, most specific first.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+func parseDate(s string) (time.Time, bool) {
+	if len(s) < len("2006-01-02") || s[4] != '-' {
+		return time.Time{}, false
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseNumber parses an integer or a float, returning an int or a float64.
+func parseNumber(s string) (any, bool) {
+	if integerLiteral.MatchString(s) {
+		if n, err := strconv.Atoi(s); err == nil {
+			return n, true
+		}
+	}
+	if floatLiteral.MatchString(s) && strings.ContainsAny(s, "0123456789") {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// splitFlow splits the inside of a flow collection, such as "a, [b, c], 'd'",
+// at the separators that are not nested in brackets, braces or quotes.
+func splitFlow(s string, sep byte) []string {
+	var parts []string
+	depth := 0
+	var quote byte
+	start := 0
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && opensQuote(s, i):
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		case c == sep && depth == 0:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+
+	if last := strings.TrimSpace(s[start:]); last != "" {
+		parts = append(parts, last)
+	}
+	return parts
+}
+
+// findUnquoted returns the index of the first occurrence of sep in s that is
+// outside quotes, or -1.
+func findUnquoted(s string, sep string) int {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && opensQuote(s, i):
+			quote = c
+		case strings.HasPrefix(s[i:], sep):
+			return i
+		}
+	}
+	return -1
+}
+
+// opensQuote reports whether the quote at s[i] starts a quoted string
+// rather than being an apostrophe inside a plain one.
+func opensQuote(s string, i int) bool {
+	return i == 0 || strings.IndexByte(" \t[{,:=", s[i-1]) >= 0
+}
+
+// unquote removes the quotes around a double- or single-quoted string,
+// resolving the escapes of double-quoted ones.
+func unquote(s string) (string, error) {
+	if len(s) < 2 || s[len(s)-1] != s[0] {
+		return "", fmt.Errorf("unterminated string %s", s)
+	}
+	if s[0] == '\'' {
+		return s[1 : len(s)-1], nil
+	}
+	unquoted, err := strconv.Unquote(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid string %s", s)
+	}
+	return unquoted, nil
+}
+
+// indentOf returns the number of leading spaces of line.
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// trimComment removes a trailing "# comment" from a line of YAML or TOML.
+func trimComment(line string) string {
+	if strings.HasPrefix(strings.TrimSpace(line), "#") {
+		return ""
+	}
+	if i := findUnquoted(line, " #"); i >= 0 {
+		line = line[:i]
+	}
+	return strings.TrimRight(line, " \t")
+}
@@ -0,0 +1,163 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want map[string]any
+	}{
+		{"empty", "", map[string]any{}},
+		{"scalars", "title: Hello\ncount: 3\nratio: 0.5\ndraft: true\nnothing: null\ntilde: ~",
+			map[string]any{"title": "Hello", "count": 3, "ratio": 0.5, "draft": true, "nothing": nil, "tilde": nil}},
+		{"quoted strings", "a: \"x: y\"\nb: 'it''s'\nc: \"tab\\tend\"\nd: \"true\"",
+			map[string]any{"a": "x: y", "b": "it's", "c": "tab\tend", "d": "true"}},
+		{"plain string with colon", "url: https://x.com/p", map[string]any{"url": "https://x.com/p"}},
+		{"comments", "# heading\ntitle: Hello # trailing\n\nnote: a#b",
+			map[string]any{"title": "Hello", "note": "a#b"}},
+		{"dates", "day: 2024-03-01\nat: 2024-03-01T10:30:00Z",
+			map[string]any{"day": date(2024, 3, 1), "at": time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)}},
+		{"nested mapping", "author:\n  name: Ann\n  links:\n    web: x.com",
+			map[string]any{"author": map[string]any{"name": "Ann", "links": map[string]any{"web": "x.com"}}}},
+		{"block sequence", "tags:\n  - go\n  - markdown", map[string]any{"tags": []any{"go", "markdown"}}},
+		{"sequence at key indentation", "tags:\n- go\n- markdown", map[string]any{"tags": []any{"go", "markdown"}}},
+		{"sequence of mappings", "people:\n  - name: Ann\n    age: 30\n  - name: Bob",
+			map[string]any{"people": []any{map[string]any{"name": "Ann", "age": 30}, map[string]any{"name": "Bob"}}}},
+		{"flow sequence", "tags: [go, 'a, b', [1, 2]]", map[string]any{"tags": []any{"go", "a, b", []any{1, 2}}}},
+		{"flow mapping", "point: {x: 1, y: two}", map[string]any{"point": map[string]any{"x": 1, "y": "two"}}},
+		{"literal block scalar", "text: |\n  line one\n  line two\nnext: 1",
+			map[string]any{"text": "line one\nline two\n", "next": 1}},
+		{"folded block scalar", "text: >\n  line one\n  line two\n\n  para\n",
+			map[string]any{"text": "line one line two\npara\n"}},
+		{"stripped block scalar", "text: |-\n  line\n", map[string]any{"text": "line"}},
+	}
+
+	for _, test := range tests {
+		got, err := parseYAML(test.src)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s:\nexpected %#v\ngot      %#v", test.name, test.want, got)
+		}
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"not a mapping", "just text"},
+		{"sequence at top level", "- a\n- b"},
+		{"missing colon", "title: Hello\nnot a pair"},
+		{"unterminated flow sequence", "tags: [a, b"},
+		{"unterminated flow mapping", "point: {x: 1"},
+		{"unterminated string", "title: \"Hello"},
+	}
+
+	for _, test := range tests {
+		if got, err := parseYAML(test.src); err == nil {
+			t.Errorf("%s: expected an error, got %#v", test.name, got)
+		}
+	}
+}
+
+func TestParseTOML(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want map[string]any
+	}{
+		{"empty", "", map[string]any{}},
+		{"scalars", "title = \"Hello\"\ncount = 3\nratio = 0.5\ndraft = false\nbig = 1_000",
+			map[string]any{"title": "Hello", "count": 3, "ratio": 0.5, "draft": false, "big": 1000}},
+		{"literal string", "path = 'C:\\dir'", map[string]any{"path": `C:\dir`}},
+		{"comments", "# heading\ntitle = \"a # b\" # trailing", map[string]any{"title": "a # b"}},
+		{"dates", "day = 2024-03-01\nat = 2024-03-01T10:30:00Z",
+			map[string]any{"day": date(2024, 3, 1), "at": time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)}},
+		{"quoted and dotted keys", "\"a b\" = 1\nsite.name = \"x\"",
+			map[string]any{"a b": 1, "site": map[string]any{"name": "x"}}},
+		{"tables", "[author]\nname = \"Ann\"\n[author.links]\nweb = \"x.com\"",
+			map[string]any{"author": map[string]any{"name": "Ann", "links": map[string]any{"web": "x.com"}}}},
+		{"arrays of tables", "[[people]]\nname = \"Ann\"\n[[people]]\nname = \"Bob\"\n[people.pet]\nkind = \"cat\"",
+			map[string]any{"people": []any{
+				map[string]any{"name": "Ann"},
+				map[string]any{"name": "Bob", "pet": map[string]any{"kind": "cat"}},
+			}}},
+		{"arrays", "tags = [\"go\", \"md\"]\nnested = [[1, 2], [3]]",
+			map[string]any{"tags": []any{"go", "md"}, "nested": []any{[]any{1, 2}, []any{3}}}},
+		{"array spanning lines", "tags = [\n  \"go\",\n  \"md\",\n]", map[string]any{"tags": []any{"go", "md"}}},
+		{"inline table", "point = { x = 1, y = \"two\" }", map[string]any{"point": map[string]any{"x": 1, "y": "two"}}},
+		{"multi-line string", "text = \"\"\"\nline one\nline \"two\"\"\"\"", map[string]any{"text": "line one\nline \"two\""}},
+		{"multi-line literal string", "text = '''\nraw \\n\n'''", map[string]any{"text": "raw \\n\n"}},
+	}
+
+	for _, test := range tests {
+		got, err := parseTOML(test.src)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s:\nexpected %#v\ngot      %#v", test.name, test.want, got)
+		}
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"missing equals sign", "title \"Hello\""},
+		{"invalid value", "title = Hello"},
+		{"unterminated array", "tags = [1, 2"},
+		{"unterminated inline table", "point = { x = 1"},
+		{"value used as table", "a = 1\n[a]\nb = 2"},
+		{"empty key", "= 1"},
+	}
+
+	for _, test := range tests {
+		if got, err := parseTOML(test.src); err == nil {
+			t.Errorf("%s: expected an error, got %#v", test.name, got)
+		}
+	}
+}
+
+func TestExtractFrontMatter(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		meta     map[string]any
+		rest     string
+	}{
+		{"yaml", "---\ntitle: Hello\n---\n# Doc\n", map[string]any{"title": "Hello"}, "# Doc\n"},
+		{"yaml closed by dots", "---\ntitle: Hello\n...\ntext\n", map[string]any{"title": "Hello"}, "text\n"},
+		{"toml", "+++\ntitle = \"Hello\"\n+++\ntext\n", map[string]any{"title": "Hello"}, "text\n"},
+		{"json", "{\"title\": \"Hello\", \"n\": 2, \"x\": 1.5, \"tags\": [1]}\ntext\n",
+			map[string]any{"title": "Hello", "n": 2, "x": 1.5, "tags": []any{1}}, "text\n"},
+		{"none", "# Doc\n", nil, "# Doc\n"},
+		{"unclosed", "---\ntitle: Hello\n", nil, "---\ntitle: Hello\n"},
+		{"invalid yaml", "---\n\n# Title\n\nSome text.\n\n---\n", nil, "---\n\n# Title\n\nSome text.\n\n---\n"},
+		{"invalid toml", "+++\nnot toml\n+++\n", nil, "+++\nnot toml\n+++\n"},
+		{"json followed by text", "{\"a\": 1} text\n", nil, "{\"a\": 1} text\n"},
+	}
+
+	for _, test := range tests {
+		meta, rest := extractFrontMatter(test.markdown)
+		if !reflect.DeepEqual(meta, test.meta) || rest != test.rest {
+			t.Errorf("%s:\nexpected %#v, %q\ngot      %#v, %q", test.name, test.meta, test.rest, meta, rest)
+		}
+	}
+}
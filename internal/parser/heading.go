@@ -0,0 +1,90 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// customHeadingID matches an explicit "{#id}" at the end of a heading.
+var customHeadingID = regexp.MustCompile(`(?:^|[ \t]+)\{#([^\s{}]+)\}$`)
+
+// newHeading builds a heading from its raw text. A trailing attribute list
+// gives the heading attributes and, with an "#id", its ID. With heading IDs
+// enabled, the heading takes the ID given by a trailing "{#id}", or else a
+// slug of its text, made unique within the document.
+func (p *parser) newHeading(level int, text string) *Heading {
+	heading := &Heading{Level: level}
+
+	if attributes, start, ok := p.trailingAttributes(text); ok {
+		setAttributes(heading, attributes)
+		text = strings.TrimRight(text[:start], " \t")
+	} else if p.opts.HeadingIDs {
+		if match := customHeadingID.FindStringSubmatchIndex(text); match != nil {
+			heading.ID = text[match[2]:match[3]]
+			text = text[:match[0]]
+		}
+	}
+
+	heading.Text = p.parseInline(text)
+
+	if p.opts.HeadingIDs && !p.skipInlines {
+		if heading.ID == "" {
+			heading.ID = p.headingIDs.unique(slugify(plainText(heading.Text)))
+		} else {
+			p.headingIDs.reserve(heading.ID)
+		}
+	}
+	return heading
+}
+
+// slugify turns heading text into an ID the way GitHub does: lower case,
+// letters, digits, underscores and hyphens kept, spaces turned into
+// hyphens and everything else dropped.
+func slugify(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsNumber(r), unicode.IsMark(r), r == '_', r == '-':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteByte('-')
+		}
+	}
+	return b.String()
+}
+
+// headingIDs records the heading IDs used in a document. It is shared with
+// child parsers. Each ID in use maps to the next suffix to try when a
+// heading slugs to it again, so repeated headings do not probe every suffix
+// taken before them.
+type headingIDs map[string]int
+
+// unique returns slug, or slug with the first free "-1", "-2", ... suffix
+// when it is already in use, and reserves the result.
+func (ids headingIDs) unique(slug string) string {
+	if slug == "" {
+		slug = "section"
+	}
+	n := ids[slug]
+	if n == 0 {
+		ids.reserve(slug)
+		return slug
+	}
+
+	id := slug + "-" + strconv.Itoa(n)
+	for ids[id] != 0 {
+		n++
+		id = slug + "-" + strconv.Itoa(n)
+	}
+	ids[slug] = n + 1
+	ids.reserve(id)
+	return id
+}
+
+func (ids headingIDs) reserve(id string) {
+	if ids[id] == 0 {
+		ids[id] = 1
+	}
+}
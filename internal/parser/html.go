@@ -0,0 +1,204 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// htmlBlockKind identifies which of the seven CommonMark start conditions
+// opened an HTML block. It decides how the block ends.
+type htmlBlockKind int
+
+const (
+	htmlBlockNone htmlBlockKind = iota
+	// htmlBlockRaw is a <pre>, <script>, <style> or <textarea> element.
+	htmlBlockRaw
+	htmlBlockComment
+	htmlBlockProcessingInstruction
+	htmlBlockDeclaration
+	htmlBlockCDATA
+	// htmlBlockTag is one of the block-level elements in htmlBlockTags.
+	htmlBlockTag
+	// htmlBlockOther is any other complete tag alone on its line. It
+	// cannot interrupt a paragraph.
+	htmlBlockOther
+)
+
+const (
+	htmlTagName       = `[A-Za-z][A-Za-z0-9-]*`
+	htmlAttributeName = `[A-Za-z_:][A-Za-z0-9_.:-]*`
+	// htmlAttributeValue is an unquoted, single-quoted or double-quoted value.
+	htmlAttributeValue = "(?:[^ \t\n\"'=<>`]+|'[^']*'|\"[^\"]*\")"
+	// htmlSpace is whitespace with at most one line ending.
+	htmlSpace         = `(?:[ \t]+\n?[ \t]*|\n[ \t]*)`
+	optionalHTMLSpace = `[ \t]*\n?[ \t]*`
+	htmlAttribute     = htmlSpace + htmlAttributeName + `(?:` + optionalHTMLSpace + `=` + optionalHTMLSpace + htmlAttributeValue + `)?`
+	htmlOpenTag       = `<` + htmlTagName + `(?:` + htmlAttribute + `)*` + optionalHTMLSpace + `/?>`
+	htmlClosingTag    = `</` + htmlTagName + optionalHTMLSpace + `>`
+)
+
+var (
+	htmlRawStart   = regexp.MustCompile(`(?i)^<(?:pre|script|style|textarea)(?:[ \t>]|$)`)
+	htmlRawEnd     = regexp.MustCompile(`(?i)</(?:pre|script|style|textarea)>`)
+	htmlTagStart   = regexp.MustCompile(`^</?([A-Za-z][A-Za-z0-9]*)(?:[ \t>]|/>|$)`)
+	htmlOtherStart = regexp.MustCompile(`^(?:` + htmlOpenTag + `|` + htmlClosingTag + `)[ \t]*$`)
+
+	rawHTMLTag = regexp.MustCompile(`^(?:` + htmlOpenTag + `|` + htmlClosingTag + `)`)
+)
+
+// htmlBlockTags are the elements that open an HTML block of kind
+// htmlBlockTag.
+var htmlBlockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "base": true,
+	"basefont": true, "blockquote": true, "body": true, "caption": true,
+	"center": true, "col": true, "colgroup": true, "dd": true,
+	"details": true, "dialog": true, "dir": true, "div": true, "dl": true,
+	"dt": true, "fieldset": true, "figcaption": true, "figure": true,
+	"footer": true, "form": true, "frame": true, "frameset": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"head": true, "header": true, "hr": true, "html": true, "iframe": true,
+	"legend": true, "li": true, "link": true, "main": true, "menu": true,
+	"menuitem": true, "nav": true, "noframes": true, "ol": true,
+	"optgroup": true, "option": true, "p": true, "param": true,
+	"search": true, "section": true, "summary": true, "table": true,
+	"tbody": true, "td": true, "tfoot": true, "th": true, "thead": true,
+	"title": true, "tr": true, "track": true, "ul": true,
+}
+
+// htmlBlockStart returns the kind of HTML block that line opens, or
+// htmlBlockNone.
+func htmlBlockStart(line string) htmlBlockKind {
+	if indentWidth(line) > 3 {
+		return htmlBlockNone
+	}
+	trimmedLine := strings.TrimLeft(line, " \t")
+	if !strings.HasPrefix(trimmedLine, "<") {
+		return htmlBlockNone
+	}
+
+	switch {
+	case htmlRawStart.MatchString(trimmedLine):
+		return htmlBlockRaw
+	case strings.HasPrefix(trimmedLine, "<!--"):
+		return htmlBlockComment
+	case strings.HasPrefix(trimmedLine, "<?"):
+		return htmlBlockProcessingInstruction
+	case strings.HasPrefix(trimmedLine, "<![CDATA["):
+		return htmlBlockCDATA
+	case len(trimmedLine) > 2 && trimmedLine[1] == '!' && isASCIILetter(trimmedLine[2]):
+		return htmlBlockDeclaration
+	}
+
+	if match := htmlTagStart.FindStringSubmatch(trimmedLine); match != nil && htmlBlockTags[strings.ToLower(match[1])] {
+		return htmlBlockTag
+	}
+	if htmlOtherStart.MatchString(trimmedLine) {
+		return htmlBlockOther
+	}
+	return htmlBlockNone
+}
+
+// endsBy reports whether line closes an HTML block of kind k. Blocks of the
+// last two kinds end at a blank line instead, which is not part of them.
+func (k htmlBlockKind) endsBy(line string) bool {
+	switch k {
+	case htmlBlockRaw:
+		return htmlRawEnd.MatchString(line)
+	case htmlBlockComment:
+		return strings.Contains(line, "-->")
+	case htmlBlockProcessingInstruction:
+		return strings.Contains(line, "?>")
+	case htmlBlockDeclaration:
+		return strings.Contains(line, ">")
+	case htmlBlockCDATA:
+		return strings.Contains(line, "]]>")
+	}
+	return false
+}
+
+func (k htmlBlockKind) endsAtBlankLine() bool {
+	return k == htmlBlockTag || k == htmlBlockOther
+}
+
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// parseHTMLBlock reads an HTML block, keeping its lines verbatim. A block
+// whose end condition is never met runs to the end of its container. A
+// "<!-- toc -->" comment becomes a TOCPlaceholder when enabled.
+func (p *parser) parseHTMLBlock() (Block, error) {
+	kind := htmlBlockStart(p.line)
+	lines := []string{p.line}
+
+	if !kind.endsBy(p.line) {
+		for {
+			next, ok := p.peekLine()
+			if !ok || kind.endsAtBlankLine() && isBlankLine(next) {
+				break
+			}
+			p.readLine()
+			lines = append(lines, p.line)
+			if kind.endsBy(p.line) {
+				break
+			}
+		}
+	}
+
+	content := strings.Join(lines, "\n") + "\n"
+	if p.isTOCComment(content) {
+		return &TOCPlaceholder{}, nil
+	}
+
+	return &HTMLBlock{
+		Content: content,
+	}, nil
+}
+
+// missingClosers records the closing sequences, such as "-->", that do not
+// occur in the rest of the text being parsed. An opener whose closer was not
+// found cannot find it further along either, so the search is not repeated
+// and text full of unclosed "<!--" stays linear.
+type missingClosers map[string]bool
+
+// closerEnd returns the offset just past the first closer in text at or
+// after from, or -1.
+func (m missingClosers) closerEnd(text string, from int, closer string) int {
+	if m[closer] {
+		return -1
+	}
+	i := strings.Index(text[from:], closer)
+	if i < 0 {
+		m[closer] = true
+		return -1
+	}
+	return from + i + len(closer)
+}
+
+// parseRawHTML parses an inline HTML tag, comment, processing instruction,
+// declaration or CDATA section at the start of text. It returns the node and
+// the number of bytes consumed.
+func parseRawHTML(text string, missing missingClosers) (*RawHTML, int, bool) {
+	end := -1
+	switch {
+	case strings.HasPrefix(text, "<!-->"):
+		end = len("<!-->")
+	case strings.HasPrefix(text, "<!--->"):
+		end = len("<!--->")
+	case strings.HasPrefix(text, "<!--"):
+		end = missing.closerEnd(text, len("<!--"), "-->")
+	case strings.HasPrefix(text, "<?"):
+		end = missing.closerEnd(text, len("<?"), "?>")
+	case strings.HasPrefix(text, "<![CDATA["):
+		end = missing.closerEnd(text, len("<![CDATA["), "]]>")
+	case len(text) > 2 && text[1] == '!' && isASCIILetter(text[2]):
+		end = missing.closerEnd(text, len("<!"), ">")
+	default:
+		end = len(rawHTMLTag.FindString(text))
+	}
+
+	if end <= 0 {
+		return nil, 0, false
+	}
+	return &RawHTML{Content: text[:end]}, end, true
+}
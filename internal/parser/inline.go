@@ -8,37 +8,141 @@ type Text struct {
 	Content string
 }
 
+// SoftBreak is a line ending inside a paragraph that is not a hard break.
+type SoftBreak struct{}
+
+// HardBreak is a line ending preceded by two or more spaces or a backslash.
+type HardBreak struct{}
+
 type Bold struct {
+	Attributes
 	Content []Inline
 }
 
 type Italic struct {
+	Attributes
 	Content []Inline
 }
 
 type BoldItalic struct {
+	Attributes
+	Content []Inline
+}
+
+// Strikethrough is GFM deleted text: "~~text~~".
+type Strikethrough struct {
+	Attributes
+	Content []Inline
+}
+
+// Highlight is marked text: "==text==".
+type Highlight struct {
+	Attributes
+	Content []Inline
+}
+
+// Subscript is text lowered below the baseline: "H~2~O".
+type Subscript struct {
+	Attributes
+	Content []Inline
+}
+
+// Superscript is text raised above the baseline: "x^2^".
+type Superscript struct {
+	Attributes
 	Content []Inline
 }
 
 type Link struct {
-	Text []Inline
-	URL  string
+	Attributes
+	Text  []Inline
+	URL   string
+	Title string
 }
 
 type CodeInline struct {
+	Attributes
+	Content string
+}
+
+// Span is bracketed text given attributes: "[text]{.class}".
+type Span struct {
+	Attributes
+	Content []Inline
+}
+
+// MathInline is a LaTeX formula: "$x^2$", or "$$x^2$$" for display math
+// inside a paragraph. Content is the formula without its dollar signs.
+type MathInline struct {
+	Attributes
+	Content string
+	Display bool
+}
+
+// FootnoteReference is a reference to a footnote: "[^label]". Number is
+// the footnote's number and Index counts the references to the same
+// footnote, starting at 1.
+type FootnoteReference struct {
+	Attributes
+	Label  string
+	Number int
+	Index  int
+}
+
+// RawHTML is an inline HTML tag, comment, processing instruction,
+// declaration or CDATA section, kept verbatim.
+type RawHTML struct {
 	Content string
 }
 
 type Image struct {
+	Attributes
 	Alt   string
 	Src   string
 	Title string
 }
 
-func (t Text) isInline()       {}
-func (b Bold) isInline()       {}
-func (i Italic) isInline()     {}
-func (l Link) isInline()       {}
-func (b BoldItalic) isInline() {}
-func (c CodeInline) isInline() {}
-func (i Image) isInline()      {}
+func (t Text) isInline()              {}
+func (s SoftBreak) isInline()         {}
+func (h HardBreak) isInline()         {}
+func (b Bold) isInline()              {}
+func (i Italic) isInline()            {}
+func (l Link) isInline()              {}
+func (b BoldItalic) isInline()        {}
+func (c CodeInline) isInline()        {}
+func (i Image) isInline()             {}
+func (r RawHTML) isInline()           {}
+func (f FootnoteReference) isInline() {}
+func (m MathInline) isInline()        {}
+func (s Span) isInline()              {}
+
+func (s Strikethrough) isInline() {}
+func (h Highlight) isInline()     {}
+func (s Subscript) isInline()     {}
+func (s Superscript) isInline()   {}
+
+// inlineChildren returns the inlines nested in a formatting inline, or nil
+// for inlines that hold no other inlines.
+func inlineChildren(inline Inline) []Inline {
+	switch i := inline.(type) {
+	case *Bold:
+		return i.Content
+	case *Italic:
+		return i.Content
+	case *BoldItalic:
+		return i.Content
+	case *Strikethrough:
+		return i.Content
+	case *Highlight:
+		return i.Content
+	case *Subscript:
+		return i.Content
+	case *Superscript:
+		return i.Content
+	case *Link:
+		return i.Text
+	case *Span:
+		return i.Content
+	}
+	return nil
+}
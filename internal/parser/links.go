@@ -0,0 +1,460 @@
+package parser
+
+import "strings"
+
+// LinkReference is a link reference definition:
+//
+//	[label]: https://example.com "Title"
+type LinkReference struct {
+	Label string
+	URL   string
+	Title string
+}
+
+// bracket is an opening "[" or "![" waiting for its "]" on the bracket
+// stack, as in the CommonMark reference algorithm. Its text stays in the
+// inline list as a Text node until it is matched.
+type bracket struct {
+	// index is the position of the bracket's Text node in the inline
+	// list.
+	index int
+	image bool
+	// active is cleared once a link is matched after the bracket, since
+	// links cannot contain other links.
+	active bool
+	// start is the offset of the bracketed text in the text being parsed.
+	start int
+	// delimiters is the length of the delimiter stack when the bracket was
+	// pushed; the delimiters above it belong to the bracketed text.
+	delimiters int
+}
+
+// matchBracket tries to close opener with the ']' at text[end], as an inline
+// link, a full, collapsed or shortcut reference link, or else, with
+// attributes enabled, a "[text]{.class}" span. It returns the node, whose
+// content the caller fills in, and the number of bytes consumed after the
+// ']'.
+func (p *parser) matchBracket(text string, end int, opener *bracket) (Inline, int, bool) {
+	linkText := text[opener.start:end]
+	rest := text[end+1:]
+	consumed := 0
+
+	var url, title string
+	found := false
+
+	if strings.HasPrefix(rest, "(") {
+		if dest, destTitle, n, ok := parseInlineLinkTail(rest); ok {
+			url, title = dest, destTitle
+			consumed = n
+			found = true
+		}
+	}
+
+	if !found {
+		// A full reference names its label; collapsed and shortcut
+		// references use the link text as the label.
+		label := linkText
+		labelOK := true
+		if strings.HasPrefix(rest, "[]") {
+			consumed = 2
+		} else if fullLabel, n, ok := parseLinkLabel(rest); ok {
+			label = fullLabel
+			consumed = n
+		} else if !isLinkLabel(linkText) {
+			labelOK = false
+		}
+
+		if reference, ok := p.references[normalizeLabel(label)]; labelOK && ok {
+			url, title = reference.URL, reference.Title
+			found = true
+		}
+	}
+
+	var attributes Attributes
+	if !found {
+		consumed = 0
+	}
+	if p.opts.Attributes {
+		if parsed, n, ok := parseAttributes(rest[consumed:]); ok {
+			attributes = parsed
+			consumed += n
+		}
+	}
+
+	switch {
+	case found && opener.image:
+		return &Image{Attributes: attributes, Src: url, Title: title}, consumed, true
+	case found:
+		return &Link{Attributes: attributes, URL: url, Title: title}, consumed, true
+	case attributes != nil && !opener.image:
+		return &Span{Attributes: attributes}, consumed, true
+	}
+	return nil, 0, false
+}
+
+// unlinkify turns the bare URLs linked by linkify among the inlines read
+// since a bracket back into text: the text of a link is not linked again.
+func unlinkify(children []Inline, linkified map[*Link]bool) {
+	for i, child := range children {
+		if link, ok := child.(*Link); ok && linkified[link] {
+			children[i] = &Text{Content: plainText(link.Text)}
+		}
+	}
+}
+
+// closeBracket gives node the inlines read since its bracket as its
+// content, once their emphasis is resolved.
+func closeBracket(node Inline, children []Inline, delimiters []*delimiter) {
+	content := processEmphasis(children, delimiters)
+
+	switch n := node.(type) {
+	case *Link:
+		n.Text = content
+	case *Image:
+		n.Alt = plainText(content)
+	case *Span:
+		n.Content = content
+	}
+}
+
+// parseInlineLinkTail parses the "(destination "title")" part of an inline
+// link at the start of text. Both the destination and the title are
+// optional. It returns them with the number of bytes consumed.
+func parseInlineLinkTail(text string) (string, string, int, bool) {
+	i := 1
+	i += skipSpace(text[i:])
+	if i < len(text) && text[i] == ')' {
+		return "", "", i + 1, true
+	}
+
+	url, n, ok := parseLinkDestination(text[i:])
+	if !ok {
+		return "", "", 0, false
+	}
+	i += n
+
+	// The title must be separated from the destination by whitespace.
+	var title string
+	if spacing := skipSpace(text[i:]); spacing > 0 {
+		i += spacing
+		if t, n, ok := parseLinkTitle(text[i:]); ok {
+			title = t
+			i += n
+			i += skipSpace(text[i:])
+		}
+	}
+
+	if i >= len(text) || text[i] != ')' {
+		return "", "", 0, false
+	}
+	return url, title, i + 1, true
+}
+
+func backtickRun(text string) int {
+	n := 0
+	for n < len(text) && text[n] == '`' {
+		n++
+	}
+	return n
+}
+
+// backtickRuns finds the closing runs of code spans in the text being
+// parsed. It remembers the offset of the last run of each length it has
+// seen, so once the whole text has been scanned, an opener with no later
+// run of its length fails without rescanning the rest of the text.
+type backtickRuns struct {
+	last    map[int]int
+	scanned bool
+}
+
+// findClosing returns the index in text[from:] of the first run of exactly
+// n backticks, or -1.
+func (b *backtickRuns) findClosing(text string, from, n int) int {
+	if last, ok := b.last[n]; b.scanned && (!ok || last < from) {
+		return -1
+	}
+	if b.last == nil {
+		b.last = make(map[int]int)
+	}
+	for i := from; i < len(text); {
+		if text[i] != '`' {
+			i++
+			continue
+		}
+		run := backtickRun(text[i:])
+		if last, ok := b.last[run]; !ok || i > last {
+			b.last[run] = i
+		}
+		if run == n {
+			return i - from
+		}
+		i += run
+	}
+	b.scanned = true
+	return -1
+}
+
+// parseLinkLabel parses a link label at the start of text: up to 999
+// characters between brackets, with no unescaped brackets inside and at
+// least one non-whitespace character. It returns the label without brackets
+// and the number of bytes consumed.
+func parseLinkLabel(text string) (string, int, bool) {
+	if !strings.HasPrefix(text, "[") {
+		return "", 0, false
+	}
+	for i := 1; i < len(text) && i <= 1000; i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '[':
+			return "", 0, false
+		case ']':
+			label := text[1:i]
+			if strings.TrimSpace(label) == "" {
+				return "", 0, false
+			}
+			return label, i + 1, true
+		}
+	}
+	return "", 0, false
+}
+
+func isLinkLabel(label string) bool {
+	_, n, ok := parseLinkLabel("[" + label + "]")
+	return ok && n == len(label)+2
+}
+
+// normalizeLabel returns the key under which a link label is matched:
+// references match case-insensitively, after Unicode case folding, with
+// runs of whitespace collapsed.
+func normalizeLabel(label string) string {
+	label = strings.Join(strings.Fields(label), " ")
+	return foldCase(label)
+}
+
+// foldCase approximates Unicode full case folding. Lowering the upper-cased
+// string folds everything that maps one to one; the sharp s is the one
+// common character that folds to two ("ẞ" and "ß" match "SS").
+func foldCase(s string) string {
+	s = strings.ToLower(strings.ToUpper(s))
+	return strings.ReplaceAll(s, "ß", "ss")
+}
+
+// parseLinkDestination parses a link destination at the start of text,
+// either enclosed in pointy brackets or as a run of non-whitespace
+// characters with balanced parentheses. It returns the destination, with
+// escapes and entities resolved, and the number of bytes consumed.
+func parseLinkDestination(text string) (string, int, bool) {
+	if strings.HasPrefix(text, "<") {
+		for i := 1; i < len(text); i++ {
+			switch text[i] {
+			case '\\':
+				i++
+			case '\n', '<':
+				return "", 0, false
+			case '>':
+				return unescapeString(text[1:i]), i + 1, true
+			}
+		}
+		return "", 0, false
+	}
+
+	depth := 0
+	i := 0
+loop:
+	for ; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && isASCIIPunctuation(text[i+1]):
+			i++
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth == 0 {
+				break loop
+			}
+			depth--
+		case c <= ' ' || c == 0x7f:
+			break loop
+		}
+	}
+
+	if i == 0 || depth != 0 {
+		return "", 0, false
+	}
+	return unescapeString(text[:i]), i, true
+}
+
+// parseLinkTitle parses a link title at the start of text, enclosed in
+// double quotes, single quotes or parentheses. A title may span lines but not
+// contain a blank line. It returns the title without its delimiters, with
+// escapes and entities resolved, and the number of bytes consumed.
+func parseLinkTitle(text string) (string, int, bool) {
+	if text == "" {
+		return "", 0, false
+	}
+
+	closer := text[0]
+	switch closer {
+	case '"', '\'':
+	case '(':
+		closer = ')'
+	default:
+		return "", 0, false
+	}
+
+	for i := 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case closer:
+			return unescapeString(text[1:i]), i + 1, true
+		case '(':
+			if closer == ')' {
+				return "", 0, false
+			}
+		case '\n':
+			if isBlankLine(lineAt(text, i+1)) {
+				return "", 0, false
+			}
+		}
+	}
+	return "", 0, false
+}
+
+// lineAt returns the rest of the line of text that starts at offset i.
+func lineAt(text string, i int) string {
+	line := text[i:]
+	if end := strings.IndexByte(line, '\n'); end >= 0 {
+		line = line[:end]
+	}
+	return line
+}
+
+// skipSpace returns the number of leading spaces and tabs in text, allowing
+// at most one line ending among them.
+func skipSpace(text string) int {
+	i := 0
+	newline := false
+	for i < len(text) {
+		switch text[i] {
+		case ' ', '\t':
+		case '\n':
+			if newline {
+				return i
+			}
+			newline = true
+		default:
+			return i
+		}
+		i++
+	}
+	return i
+}
+
+// parseLinkReferenceDefinition parses a link reference definition at the
+// start of text and returns it with the number of bytes consumed, including
+// the line ending.
+func parseLinkReferenceDefinition(text string) (*LinkReference, int, bool) {
+	label, i, ok := parseLinkLabel(text)
+	if !ok || i >= len(text) || text[i] != ':' {
+		return nil, 0, false
+	}
+	i++
+	i += skipSpace(text[i:])
+
+	url, n, ok := parseLinkDestination(text[i:])
+	if !ok {
+		return nil, 0, false
+	}
+	i += n
+	afterURL := i
+
+	// The title must be separated from the destination by whitespace, and
+	// nothing but whitespace may follow it on its line. A title that does
+	// not fit these rules leaves a definition without one, provided the
+	// destination ends its line.
+	spacing := skipSpace(text[i:])
+	if spacing > 0 {
+		if title, n, ok := parseLinkTitle(text[i+spacing:]); ok {
+			end := i + spacing + n
+			if rest := lineAt(text, end); isBlankLine(rest) {
+				return &LinkReference{
+					Label: label,
+					URL:   url,
+					Title: title,
+				}, consumeLine(text, end), true
+			}
+		}
+	}
+
+	if !isBlankLine(lineAt(text, afterURL)) {
+		return nil, 0, false
+	}
+	return &LinkReference{
+		Label: label,
+		URL:   url,
+	}, consumeLine(text, afterURL), true
+}
+
+// consumeLine returns the offset just past the line ending that follows
+// offset i in text.
+func consumeLine(text string, i int) int {
+	if end := strings.IndexByte(text[i:], '\n'); end >= 0 {
+		return i + end + 1
+	}
+	return len(text)
+}
+
+// extractLinkReferences removes the link reference definitions that open a
+// paragraph's text, recording each one unless its label is already defined.
+func (p *parser) extractLinkReferences(text string) string {
+	for strings.HasPrefix(text, "[") {
+		reference, n, ok := parseLinkReferenceDefinition(text)
+		if !ok {
+			break
+		}
+		key := normalizeLabel(reference.Label)
+		if _, defined := p.references[key]; !defined {
+			p.references[key] = reference
+		}
+		text = text[n:]
+	}
+	return text
+}
+
+func containsLink(inlines []Inline) bool {
+	for _, inline := range inlines {
+		if _, ok := inline.(*Link); ok || containsLink(inlineChildren(inline)) {
+			return true
+		}
+	}
+	return false
+}
+
+// plainText flattens inlines to their text content, as used for the alt
+// text of images.
+func plainText(inlines []Inline) string {
+	var b strings.Builder
+	for _, inline := range inlines {
+		switch i := inline.(type) {
+		case *Text:
+			b.WriteString(i.Content)
+		case *CodeInline:
+			b.WriteString(i.Content)
+		case *MathInline:
+			b.WriteString(i.Content)
+		case *SoftBreak, *HardBreak:
+			b.WriteString("\n")
+		case *Image:
+			b.WriteString(i.Alt)
+		default:
+			b.WriteString(plainText(inlineChildren(inline)))
+		}
+	}
+	return b.String()
+}
+
+func isASCIIPunctuation(c byte) bool {
+	return c >= '!' && c <= '/' || c >= ':' && c <= '@' || c >= '[' && c <= '`' || c >= '{' && c <= '~'
+}
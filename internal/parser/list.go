@@ -0,0 +1,198 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	orderedListMarker = regexp.MustCompile(`^(\d{1,9})([.)])([ \t]|$)`)
+	taskListMarker    = regexp.MustCompile(`^\[([ xX])\][ \t]+\S`)
+)
+
+// listMarker describes the marker that opens a list item.
+type listMarker struct {
+	listType ListType
+	start    int
+	// delimiter is the bullet character of an unordered item or the '.'
+	// or ')' following the number of an ordered one.
+	delimiter byte
+	// width is the column at which the item's content starts; continuation
+	// lines must be indented at least this far to belong to the item.
+	width int
+	// content is the rest of the marker line with the marker stripped.
+	content string
+}
+
+// parseListMarker recognises a bullet ("- ", "+ ", "* ") or ordered ("1. ",
+// "1) ") list marker indented by at most three spaces.
+func (p *parser) parseListMarker(line string) (listMarker, bool) {
+	indent := indentWidth(line)
+	if indent > 3 || isThematicBreak(line) {
+		return listMarker{}, false
+	}
+	trimmedLine := strings.TrimLeft(line, " \t")
+
+	marker := listMarker{}
+	markerLen := 0
+
+	if len(trimmedLine) > 0 && strings.IndexByte("-+*", trimmedLine[0]) >= 0 {
+		if len(trimmedLine) > 1 && trimmedLine[1] != ' ' && trimmedLine[1] != '\t' {
+			return listMarker{}, false
+		}
+		marker.listType = UnorderedList
+		marker.delimiter = trimmedLine[0]
+		markerLen = 1
+	} else if match := orderedListMarker.FindStringSubmatch(trimmedLine); match != nil {
+		marker.listType = OrderedList
+		marker.start, _ = strconv.Atoi(match[1])
+		marker.delimiter = match[2][0]
+		markerLen = len(match[1]) + 1
+	} else {
+		return listMarker{}, false
+	}
+
+	column := indent + markerLen
+	rest := expandIndent(trimmedLine[markerLen:], column)
+	spacing := indentWidth(rest)
+
+	switch {
+	case isBlankLine(rest):
+		// An item that starts with a blank line has its content one
+		// column after the marker.
+		marker.width = column + 1
+		marker.content = ""
+	case spacing > 4:
+		// Five or more spaces start an indented code block; only one of
+		// them belongs to the marker.
+		marker.width = column + 1
+		marker.content = stripColumns(rest, 1)
+	default:
+		marker.width = column + spacing
+		marker.content = strings.TrimLeft(rest, " \t")
+	}
+
+	if p.opts.FixedListIndent > 0 {
+		marker.width = indent + p.opts.FixedListIndent
+	}
+
+	return marker, true
+}
+
+func (p *parser) isListMarker(line string) bool {
+	_, ok := p.parseListMarker(line)
+	return ok
+}
+
+func (p *parser) parseList() (*List, error) {
+	marker, _ := p.parseListMarker(p.line)
+	list := &List{
+		Type:      marker.listType,
+		Start:     marker.start,
+		Delimiter: marker.delimiter,
+		Tight:     true,
+	}
+
+	for {
+		lines, lazy := p.collectListItemLines(marker)
+		item := &ListItem{}
+
+		if p.opts.TaskLists {
+			if match := taskListMarker.FindStringSubmatch(lines[0]); match != nil {
+				checked := match[1] != " "
+				item.Checked = &checked
+				lines[0] = strings.TrimLeft(lines[0][3:], " \t")
+			}
+		}
+
+		children, blankBetweenBlocks, err := p.parseContainer(lines, lazy)
+		if err != nil {
+			return nil, err
+		}
+		if blankBetweenBlocks {
+			list.Tight = false
+		}
+		item.Children = children
+		list.Items = append(list.Items, item)
+
+		// Look past blank lines for the next item of the same list. A
+		// different bullet character or delimiter starts a new list.
+		mark := p.pos
+		blanks := 0
+		next, ok := p.peekLine()
+		for ok && isBlankLine(next) {
+			p.readLine()
+			blanks++
+			next, ok = p.peekLine()
+		}
+
+		nextMarker, isListItem := p.parseListMarker(next)
+		if !ok || !isListItem || nextMarker.delimiter != marker.delimiter {
+			p.pos = mark
+			break
+		}
+
+		if blanks > 0 {
+			list.Tight = false
+		}
+		p.readLine()
+		marker = nextMarker
+	}
+
+	return list, nil
+}
+
+// collectListItemLines consumes the lines that belong to the list item opened
+// by marker on the current line and returns them with the item's content
+// indentation removed. Trailing blank lines are left unread so the caller can
+// tell whether the item was followed by a blank line. The indexes of lazy
+// continuation lines are returned alongside.
+func (p *parser) collectListItemLines(marker listMarker) ([]string, map[int]bool) {
+	lines := []string{marker.content}
+	lazy := make(map[int]bool)
+	paragraph := lazyTracker{}
+	paragraph.add(p, marker.content)
+
+	mark := p.pos
+	blanks := 0
+
+	for {
+		next, ok := p.peekLine()
+		if !ok {
+			break
+		}
+
+		if isBlankLine(next) {
+			// An item can begin with at most one blank line.
+			if len(lines) == 1 && marker.content == "" {
+				break
+			}
+			p.readLine()
+			lines = append(lines, "")
+			paragraph.add(p, "")
+			blanks++
+			continue
+		}
+
+		if indentWidth(next) >= marker.width {
+			p.readLine()
+			content := stripColumns(expandIndent(next, 0), marker.width)
+			lines = append(lines, content)
+			paragraph.add(p, content)
+		} else if blanks == 0 && paragraph.open && !p.interruptsParagraph(next) && !p.isListMarker(next) && !p.isAttributeLine(next) {
+			// Lazy continuation line of the item's open paragraph.
+			p.readLine()
+			lazy[len(lines)] = true
+			lines = append(lines, next)
+		} else {
+			break
+		}
+
+		mark = p.pos
+		blanks = 0
+	}
+
+	p.pos = mark
+	return lines[:len(lines)-blanks], lazy
+}
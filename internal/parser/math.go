@@ -0,0 +1,90 @@
+package parser
+
+import "strings"
+
+// parseInlineMath parses "$...$" or "$$...$$" math at the start of text.
+// Like pandoc, it needs the opening "$" to be followed, and the closing one
+// to be preceded, by a non-space, and the closing one not to be followed by
+// a digit, so that prices such as "$5 and $10" stay text. The closing "$" is
+// the next unescaped one; when that cannot close the formula there is none,
+// which keeps a line full of dollar signs linear. A backslash escapes the
+// character after it inside the formula.
+func parseInlineMath(text string) (*MathInline, int, bool) {
+	if strings.HasPrefix(text, "$$") {
+		for i := 2; i < len(text)-1; i++ {
+			switch {
+			case text[i] == '\\':
+				i++
+			case text[i] == '$' && text[i+1] == '$':
+				content := strings.TrimSpace(text[2:i])
+				if content == "" {
+					return nil, 0, false
+				}
+				return &MathInline{Content: content, Display: true}, i + 2, true
+			}
+		}
+		return nil, 0, false
+	}
+
+	if len(text) < 3 || isMathSpace(text[1]) || text[1] == '$' {
+		return nil, 0, false
+	}
+	for i := 1; i < len(text); i++ {
+		switch {
+		case text[i] == '\\':
+			i++
+		case text[i] == '$':
+			if isMathSpace(text[i-1]) || i+1 < len(text) && isDigit(text[i+1]) {
+				return nil, 0, false
+			}
+			return &MathInline{Content: text[1:i]}, i + 1, true
+		}
+	}
+	return nil, 0, false
+}
+
+func isMathSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isMathBlockStart reports whether line opens a math block: "$$" indented
+// by at most three spaces, either alone or followed by a formula that the
+// line also closes with "$$".
+func (p *parser) isMathBlockStart(line string) bool {
+	if !p.opts.Math || indentWidth(line) > 3 {
+		return false
+	}
+	trimmedLine := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmedLine, "$$") {
+		return false
+	}
+	rest := trimmedLine[2:]
+	end := strings.Index(rest, "$$")
+	return end < 0 || end == len(rest)-2
+}
+
+// parseMathBlock parses a math block that runs from a "$$" line to the next
+// line ending in "$$", or to the end of its container when unclosed.
+func (p *parser) parseMathBlock() (*MathBlock, error) {
+	first := strings.TrimSpace(p.line)[2:]
+	if content, ok := strings.CutSuffix(first, "$$"); ok {
+		return &MathBlock{Content: strings.TrimSpace(content)}, nil
+	}
+
+	lines := []string{first}
+	for p.pos < len(p.input) {
+		p.readLine()
+		line := strings.TrimRight(p.line, " \t")
+		if content, ok := strings.CutSuffix(line, "$$"); ok {
+			lines = append(lines, content)
+			break
+		}
+		lines = append(lines, p.line)
+	}
+
+	return &MathBlock{Content: strings.TrimSpace(strings.Join(lines, "\n"))}, nil
+}
@@ -0,0 +1,98 @@
+package parser
+
+// Options controls which markdown dialect the parser accepts.
+type Options struct {
+	// CommonMark restricts the parser to the CommonMark spec. Every
+	// extension below is ignored while it is set.
+	CommonMark bool
+
+	// Tables enables GFM pipe tables.
+	Tables bool
+
+	// TaskLists enables GFM task list items: "- [ ] todo" and "- [x] done".
+	TaskLists bool
+
+	// Strikethrough enables GFM "~~deleted~~" text.
+	Strikethrough bool
+
+	// Highlight enables "==marked==" text.
+	Highlight bool
+
+	// Subscript enables "H~2~O" subscripts.
+	Subscript bool
+
+	// Superscript enables "x^2^" superscripts.
+	Superscript bool
+
+	// Footnotes enables footnote references, "[^1]", and definitions,
+	// "[^1]: note".
+	Footnotes bool
+
+	// FrontMatter enables YAML ("---"), TOML ("+++") and JSON front
+	// matter at the start of the document, parsed into Document.Meta.
+	FrontMatter bool
+
+	// HeadingIDs gives every heading an ID: the slug of its text, or the
+	// ID given by a trailing "{#id}".
+	HeadingIDs bool
+
+	// TOC turns a "[TOC]" paragraph or a "<!-- toc -->" comment into a
+	// TOCPlaceholder.
+	TOC bool
+
+	// Admonitions turns GitHub alerts, "> [!NOTE]", and fenced containers,
+	// ":::warning ... :::", into admonitions.
+	Admonitions bool
+
+	// Math enables LaTeX math: "$inline$", "$$display$$" and "$$" blocks.
+	// Formulas are kept verbatim, out of reach of inline parsing.
+	Math bool
+
+	// DefinitionLists enables PHP Markdown Extra definition lists: lines
+	// of terms followed by ": definition" lines.
+	DefinitionLists bool
+
+	// Attributes enables attribute lists, "{#id .class key=value}", after
+	// headings and images, links, code spans and "[bracketed]" spans, and
+	// on a line of their own after a block.
+	Attributes bool
+
+	// Linkify enables GFM extended autolinks: bare "https://..." and
+	// "www...." URLs become links.
+	Linkify bool
+
+	// FixedListIndent, when non-zero, makes lines indented by that many
+	// columns past a list marker belong to the item, whatever the width of
+	// the marker. CommonMark instead lines them up with the item's content
+	// column. Setting it to 2 restores madopa's original nesting rules.
+	FixedListIndent int
+}
+
+// DefaultOptions returns the options used by Parse: CommonMark plus the GFM
+// extensions and those that only claim syntax plain prose does not use.
+// Highlight, Subscript, Superscript and Math are left off, since "~", "^"
+// and "$" are common in ordinary text such as shell commands and prices.
+func DefaultOptions() Options {
+	return Options{
+		Tables:          true,
+		TaskLists:       true,
+		Linkify:         true,
+		Strikethrough:   true,
+		Footnotes:       true,
+		FrontMatter:     true,
+		HeadingIDs:      true,
+		TOC:             true,
+		Admonitions:     true,
+		DefinitionLists: true,
+		Attributes:      true,
+	}
+}
+
+// effective returns a copy of the options with every extension switched off
+// when CommonMark mode is requested.
+func (o Options) effective() Options {
+	if o.CommonMark {
+		return Options{CommonMark: true}
+	}
+	return o
+}
@@ -2,83 +2,156 @@ package parser
 
 import (
 	"fmt"
-	"regexp"
 	"strings"
-	"unicode"
 )
 
-type Document struct {
-	Blocks []Block
-}
-
 type parser struct {
 	input string
 	pos   int
 	line  string
+	opts  Options
+
+	// blankBetweenBlocks records whether a blank line separated two of the
+	// parsed blocks, which makes an enclosing list loose.
+	blankBetweenBlocks bool
+
+	// lazyLines holds the offsets of lines that were lazy continuation
+	// lines of an enclosing container. They can only continue a paragraph,
+	// never turn it into a setext heading.
+	lazyLines map[int]bool
+
+	// references holds the link reference definitions of the document,
+	// keyed by normalized label. It is shared with child parsers.
+	references map[string]*LinkReference
+
+	// footnotes indexes the footnotes of the document. It is shared with
+	// child parsers.
+	footnotes *footnoteIndex
+
+	// headingIDs holds the heading IDs already in use. It is shared with
+	// child parsers.
+	headingIDs headingIDs
+
+	// skipInlines is set on the first pass over the document, which only
+	// collects link reference definitions.
+	skipInlines bool
+
+	// depth is the number of container blocks the input is nested in.
+	depth int
 }
 
+// maxContainerDepth is how deeply container blocks nest. Each level copies
+// the lines of its content, so past it block quote, list, admonition,
+// footnote and definition markers are left as text rather than letting
+// deeply nested input take quadratic time.
+const maxContainerDepth = 32
+
 func (p *parser) parse() (*Document, error) {
-	doc := &Document{
-		Blocks: make([]Block, 0),
+	blocks, err := p.parseBlocks()
+	if err != nil {
+		return nil, err
 	}
 
+	return &Document{
+		Blocks:     blocks,
+		References: p.references,
+		Footnotes:  p.footnotes.referencedFootnotes(),
+	}, nil
+}
+
+func (p *parser) parseBlocks() ([]Block, error) {
+	blocks := make([]Block, 0)
+	sawBlank := false
+	parsed := 0
+
 	for p.pos < len(p.input) {
 		p.readLine()
 
-		if strings.TrimSpace(p.line) == "" {
+		if isBlankLine(p.line) {
+			sawBlank = true
 			continue
 		}
 
+		// An attribute list on the line after a block applies to it.
+		if len(blocks) > 0 && !sawBlank {
+			if attributes, ok := p.parseAttributeLine(p.line); ok {
+				setAttributes(blocks[len(blocks)-1], attributes)
+				continue
+			}
+		}
+
 		block, err := p.parseBlock(p.line)
-		// fmt.Printf("Block type: %T\n", block)
 		if err != nil {
 			return nil, err
 		}
 
-		doc.Blocks = append(doc.Blocks, block)
+		if sawBlank && parsed > 0 {
+			p.blankBetweenBlocks = true
+		}
+		sawBlank = false
+		parsed++
+
+		// Paragraphs made only of link reference definitions produce no
+		// block.
+		if block != nil {
+			blocks = append(blocks, block)
+		}
 	}
 
-	return doc, nil
+	return blocks, nil
 }
 
 func (p *parser) parseBlock(line string) (Block, error) {
-	trimmedLine := strings.TrimLeftFunc(line, unicode.IsSpace)
+	// Indented code cannot interrupt a paragraph, so a line indented by
+	// four or more columns always starts one here.
+	if indentWidth(line) > 3 {
+		return p.parseIndentedCodeBlock()
+	}
 
-	if strings.HasPrefix(trimmedLine, "#") {
+	if atxHeadingLevel(line) > 0 {
 		return p.parseHeading()
 	}
 
-	if strings.HasPrefix(trimmedLine, "```") {
+	if isThematicBreak(line) {
+		return &ThematicBreak{}, nil
+	}
+
+	if isCodeFence(line) {
 		return p.parseCodeBlock()
 	}
 
-	if strings.Contains(trimmedLine, "|") {
-		currentPos := p.pos
-		currentLine := p.line
+	nest := p.depth < maxContainerDepth
 
-		if p.pos < len(p.input) {
-			originalPos := p.pos
-			p.readLine()
+	if nest && p.isAdmonitionFence(line) {
+		return p.parseFencedAdmonition()
+	}
 
-			if strings.Contains(p.line, "|") && strings.Contains(p.line, "-") {
-				p.pos = currentPos
-				p.line = currentLine
-				return p.parseTable()
-			}
+	if p.isMathBlockStart(line) {
+		return p.parseMathBlock()
+	}
 
-			// Restore the pos to the original position
-			p.pos = originalPos
-			p.readLine()
-		}
+	if htmlBlockStart(line) != htmlBlockNone {
+		return p.parseHTMLBlock()
 	}
 
-	if strings.HasPrefix(trimmedLine, ">") {
+	if p.startsTable(line) {
+		return p.parseTable()
+	}
+
+	if _, ok := stripBlockquoteMarker(line); ok && nest {
 		return p.parseBlockquote()
 	}
 
-	_, _, ok, _ := p.parseListItem(p.line)
-	if ok {
-		return p.parseList(p.line)
+	if _, ok := p.parseListMarker(line); ok && nest {
+		return p.parseList()
+	}
+
+	if nest && p.isFootnoteDefinition(line) {
+		return p.parseFootnoteDefinition()
+	}
+
+	if nest && p.startsDefinitionList() {
+		return p.parseDefinitionList()
 	}
 
 	return p.parseParagraph()
@@ -93,222 +166,494 @@ func (p *parser) readLine() {
 	p.pos += end + 1
 }
 
-func (p *parser) parseParagraph() (*Paragraph, error) {
-	return &Paragraph{
-		Text: p.parseInline(p.line),
-	}, nil
+// peekLine returns the line after the current one without consuming it.
+func (p *parser) peekLine() (string, bool) {
+	if p.pos >= len(p.input) {
+		return "", false
+	}
+	end := strings.IndexByte(p.input[p.pos:], '\n')
+	if end == -1 {
+		end = len(p.input) - p.pos
+	}
+	return p.input[p.pos : p.pos+end], true
 }
 
-func (p *parser) parseHeading() (*Heading, error) {
-	level := 0
-	for _, char := range p.line {
-		if char == '#' {
-			level++
-		} else {
-			break
+func isBlankLine(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// startsTable reports whether line is a table header row, i.e. whether the
+// line after it is a delimiter row with the same number of cells.
+func (p *parser) startsTable(line string) bool {
+	if !p.opts.Tables || !strings.Contains(line, "|") {
+		return false
+	}
+	next, ok := p.peekLine()
+	if !ok || !isTableDelimiterRow(next) {
+		return false
+	}
+	return len(p.parseTableAlignments(next)) == len(splitTableRow(line))
+}
+
+// isTableDelimiterRow reports whether line only holds pipes, dashes, colons
+// and whitespace. Requiring a pipe keeps a plain "---" a setext underline or
+// thematic break.
+func isTableDelimiterRow(line string) bool {
+	if indentWidth(line) > 3 || !strings.Contains(line, "|") || !strings.Contains(line, "-") {
+		return false
+	}
+	return strings.Trim(line, "|-: \t") == ""
+}
+
+// interruptsParagraph reports whether line starts a block that ends an open
+// paragraph. Any other non-blank line continues the paragraph, which is also
+// what allows lazy continuation lines inside lists and blockquotes.
+func (p *parser) interruptsParagraph(line string) bool {
+	if isBlankLine(line) || atxHeadingLevel(line) > 0 || isThematicBreak(line) {
+		return true
+	}
+
+	if indentWidth(line) > 3 {
+		return false
+	}
+
+	if isCodeFence(line) || p.isAdmonitionFence(line) || p.isMathBlockStart(line) {
+		return true
+	}
+
+	// Any HTML block but a lone tag of an arbitrary element.
+	if kind := htmlBlockStart(line); kind != htmlBlockNone && kind != htmlBlockOther {
+		return true
+	}
+
+	if _, ok := stripBlockquoteMarker(line); ok {
+		return true
+	}
+
+	if p.isFootnoteDefinition(line) {
+		return true
+	}
+
+	// A paragraph followed by a definition is a term instead, so a
+	// definition never continues one.
+	if p.isDefinitionMarker(line) {
+		return true
+	}
+
+	// Only a non-empty list item can interrupt a paragraph, and an ordered
+	// one only when it starts at 1.
+	if marker, ok := p.parseListMarker(line); ok && !isBlankLine(marker.content) {
+		return marker.listType == UnorderedList || marker.start == 1
+	}
+
+	if p.opts.Tables && strings.Contains(line, "|") {
+		currentPos := p.pos
+		currentLine := p.line
+		p.readLine()
+		isTable := p.startsTable(p.line)
+		p.pos = currentPos
+		p.line = currentLine
+		return isTable
+	}
+
+	return false
+}
+
+// indentWidth returns the number of columns of leading whitespace in line,
+// expanding tabs to the next multiple of four.
+func indentWidth(line string) int {
+	width := 0
+	for _, char := range line {
+		switch char {
+		case ' ':
+			width++
+		case '\t':
+			width += 4 - width%4
+		default:
+			return width
 		}
 	}
-	if level > 6 {
-		level = 6
+	return width
+}
+
+// isThematicBreak reports whether line is a thematic break: three or more
+// matching '-', '*' or '_' characters, optionally separated by whitespace and
+// indented by at most three spaces.
+func isThematicBreak(line string) bool {
+	if indentWidth(line) > 3 {
+		return false
 	}
 
-	text := strings.TrimSpace(strings.TrimPrefix(p.line, strings.Repeat("#", level)))
+	var marker rune
+	count := 0
+	for _, char := range line {
+		switch char {
+		case ' ', '\t':
+			continue
+		case '-', '*', '_':
+			if marker != 0 && char != marker {
+				return false
+			}
+			marker = char
+			count++
+		default:
+			return false
+		}
+	}
+	return count >= 3
+}
 
-	return &Heading{
-		Level: level,
-		Text:  p.parseInline(text),
-	}, nil
+// setextHeadingLevel reports the level of the setext heading underlined by
+// line ('=' for level 1, '-' for level 2), or 0 when line is not an
+// underline.
+func setextHeadingLevel(line string) int {
+	if indentWidth(line) > 3 {
+		return 0
+	}
+
+	underline := strings.TrimRight(strings.TrimLeft(line, " \t"), " \t")
+	switch {
+	case underline == "":
+		return 0
+	case strings.Trim(underline, "=") == "":
+		return 1
+	case strings.Trim(underline, "-") == "":
+		return 2
+	}
+	return 0
 }
 
-func (p *parser) parseCodeBlock() (*CodeBlock, error) {
-	language := strings.TrimSpace(strings.TrimPrefix(p.line, "```"))
-	codeContent := ""
+// parseParagraph parses a paragraph, or a setext heading when the paragraph
+// is followed by an underline. Link reference definitions at the start of the
+// paragraph are recorded and removed from its text; when nothing else is
+// left, it returns a nil block.
+func (p *parser) parseParagraph() (Block, error) {
+	lines := []string{strings.TrimLeft(p.line, " \t")}
 
-	for p.pos < len(p.input) {
-		p.readLine()
-		if strings.TrimSpace(p.line) == "```" {
+	for {
+		next, ok := p.peekLine()
+		if !ok {
 			break
 		}
-		codeContent += p.line + "\n"
+
+		// An underline takes precedence over a thematic break, but it
+		// cannot underline a paragraph made only of definitions.
+		if level := setextHeadingLevel(next); level > 0 && !p.lazyLines[p.pos] {
+			text := strings.TrimRight(strings.Join(lines, "\n"), " \t")
+			if text = p.extractLinkReferences(text); text != "" {
+				p.readLine()
+				return p.newHeading(level, text), nil
+			}
+		}
+
+		if p.interruptsParagraph(next) {
+			break
+		}
+		p.readLine()
+		lines = append(lines, strings.TrimLeft(p.line, " \t"))
+	}
+
+	// A last line made only of an attribute list applies to the
+	// paragraph.
+	var attributes Attributes
+	if len(lines) > 1 {
+		if parsed, ok := p.parseAttributeLine(lines[len(lines)-1]); ok {
+			attributes = parsed
+			lines = lines[:len(lines)-1]
+		}
 	}
 
-	return &CodeBlock{
-		Lang: language,
-		Code: strings.TrimSpace(codeContent),
+	text := strings.TrimRight(strings.Join(lines, "\n"), " \t")
+	if text = p.extractLinkReferences(text); text == "" {
+		return nil, nil
+	}
+
+	if p.isTOCPlaceholder(text) {
+		return &TOCPlaceholder{Attributes: attributes}, nil
+	}
+
+	return &Paragraph{
+		Attributes: attributes,
+		Text:       p.parseInline(text),
 	}, nil
 }
 
+// atxHeadingLevel reports the level of the ATX heading opened by line, or 0
+// when the line is not a heading. The opening sequence is one to six '#'
+// characters, indented by at most three spaces and followed by whitespace or
+// the end of the line.
+func atxHeadingLevel(line string) int {
+	if indentWidth(line) > 3 {
+		return 0
+	}
+	trimmedLine := strings.TrimLeft(line, " \t")
+
+	level := 0
+	for level < len(trimmedLine) && trimmedLine[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return 0
+	}
+	if level < len(trimmedLine) && trimmedLine[level] != ' ' && trimmedLine[level] != '\t' {
+		return 0
+	}
+	return level
+}
+
+func (p *parser) parseHeading() (*Heading, error) {
+	level := atxHeadingLevel(p.line)
+	text := strings.TrimLeft(p.line, " \t")[level:]
+	text = strings.Trim(text, " \t")
+
+	// Drop the optional closing sequence: a run of '#' that is either the
+	// whole content or preceded by a space.
+	closing := strings.TrimRight(text, "#")
+	if closing == "" {
+		text = ""
+	} else if len(closing) < len(text) && (strings.HasSuffix(closing, " ") || strings.HasSuffix(closing, "\t")) {
+		text = strings.TrimRight(closing, " \t")
+	}
+
+	return p.newHeading(level, text), nil
+}
+
 func (p *parser) parseInline(text string) []Inline {
+	if p.skipInlines {
+		return nil
+	}
+
 	var inlines []Inline
+	var delimiters []*delimiter
+	var brackets []*bracket
+	linkified := make(map[*Link]bool)
+	missing := make(missingClosers)
+	var backticks backtickRuns
 	var currentText strings.Builder
 	var i int
 
 	for i < len(text) {
-		// Nested bold and italic text
-		if strings.HasPrefix(text[i:], "***") {
-			if currentText.Len() > 0 {
-				inlines = append(inlines, &Text{Content: currentText.String()})
-				currentText.Reset()
+		// Line endings become soft breaks, or hard breaks when preceded by
+		// two or more spaces or a backslash.
+		if text[i] == '\n' || strings.HasPrefix(text[i:], "\\\n") {
+			content := currentText.String()
+			trimmed := strings.TrimRight(content, " ")
+			currentText.Reset()
+			if trimmed != "" {
+				inlines = append(inlines, &Text{Content: trimmed})
 			}
 
-			end := strings.Index(text[i+3:], "***")
-			if end != -1 {
-				boldItalicText := text[i+3 : i+3+end]
-				inlines = append(inlines, &BoldItalic{Content: p.parseInline(boldItalicText)})
-				i += (3 + end + 3)
-				continue
+			if text[i] == '\\' {
+				inlines = append(inlines, &HardBreak{})
+				i += 2
+			} else if len(content)-len(trimmed) >= 2 {
+				inlines = append(inlines, &HardBreak{})
+				i++
 			} else {
-				currentText.WriteString("***")
-				i += 3
-				continue
+				inlines = append(inlines, &SoftBreak{})
+				i++
+			}
+
+			for i < len(text) && (text[i] == ' ' || text[i] == '\t') {
+				i++
 			}
+			continue
 		}
 
-		// Parse bold text
-		if strings.HasPrefix(text[i:], "**") || strings.HasPrefix(text[i:], "__") {
-			marker := text[i : i+2]
+		// Backslash escapes turn ASCII punctuation into literal text
+		if text[i] == '\\' && i+1 < len(text) && isASCIIPunctuation(text[i+1]) {
+			currentText.WriteByte(text[i+1])
+			i += 2
+			continue
+		}
 
-			if currentText.Len() > 0 {
-				inlines = append(inlines, &Text{Content: currentText.String()})
-				currentText.Reset()
+		// Entity and numeric character references
+		if text[i] == '&' {
+			if decoded, n, ok := parseEntity(text[i:]); ok {
+				currentText.WriteString(decoded)
+				i += n
+				continue
 			}
+		}
 
-			end := strings.Index(text[i+2:], marker)
-			if end != -1 {
-				boldText := text[i+2 : i+2+end]
-				inlines = append(inlines, &Bold{Content: p.parseInline(boldText)})
-				i += (2 + end + 2)
-				continue
-			} else {
-				currentText.WriteString(marker)
-				i += 2
+		// Math is kept verbatim, so that "_" and "*" in formulas are not
+		// taken for emphasis
+		if p.opts.Math && text[i] == '$' {
+			if math, n, ok := parseInlineMath(text[i:]); ok {
+				if currentText.Len() > 0 {
+					inlines = append(inlines, &Text{Content: currentText.String()})
+					currentText.Reset()
+				}
+				inlines = append(inlines, math)
+				i += n
 				continue
 			}
 		}
 
-		// Parse italic text
-		if strings.HasPrefix(text[i:], "_") || strings.HasPrefix(text[i:], "*") {
-			marker := text[i : i+1]
+		// Delimiter runs are matched up once the whole text is read
+		if strings.IndexByte("*_~=^", text[i]) >= 0 {
+			d := scanDelimiterRun(text, i)
+			if !p.isDelimiter(d) {
+				currentText.WriteString(d.node.Content)
+				i += d.count
+				continue
+			}
 
 			if currentText.Len() > 0 {
 				inlines = append(inlines, &Text{Content: currentText.String()})
 				currentText.Reset()
 			}
 
-			end := strings.Index(text[i+1:], marker)
-			if end != -1 {
-				italicText := text[i+1 : i+1+end]
-				inlines = append(inlines, &Italic{Content: p.parseInline(italicText)})
-				i += (1 + end + 1)
-				continue
-			} else {
-				currentText.WriteString(marker)
-				i++
+			d.id = len(delimiters)
+			delimiters = append(delimiters, d)
+			inlines = append(inlines, d.node)
+			i += d.count
+			continue
+		}
+
+		// Footnote references
+		if p.opts.Footnotes && strings.HasPrefix(text[i:], "[^") {
+			if reference, n, ok := p.parseFootnoteReference(text[i:]); ok {
+				if currentText.Len() > 0 {
+					inlines = append(inlines, &Text{Content: currentText.String()})
+					currentText.Reset()
+				}
+				inlines = append(inlines, reference)
+				i += n
 				continue
 			}
 		}
 
-		// Parse link
-		if strings.HasPrefix(text[i:], "[") {
+		// Links and images open with a bracket that is matched when its
+		// "]" is read
+		if text[i] == '[' || strings.HasPrefix(text[i:], "![") {
 			if currentText.Len() > 0 {
 				inlines = append(inlines, &Text{Content: currentText.String()})
 				currentText.Reset()
 			}
 
-			end := strings.Index(text[i+1:], "]")
-			// fmt.Println(text[i+1:i+1+end], string(text[i]), string(text[i+end+1]))
-
-			if end != -1 && text[i+2+end] == '(' {
-				// fmt.Println("valid link")
-				linkText := text[i+1 : i+1+end]
-				linkEnd := strings.Index(text[i+1+end+1:], ")")
-				if linkEnd != -1 {
-					linkURL := text[i+end+3 : i+end+linkEnd+2]
-					inlines = append(inlines, &Link{
-						Text: p.parseInline(linkText),
-						URL:  linkURL})
-					i += (1 + end + 1 + linkEnd + 1)
-					continue
-				} else {
-					currentText.WriteString("[")
-					i++
-					continue
-				}
-
-			} else {
-				currentText.WriteString("[")
-				i++
-				continue
+			n := 1
+			if text[i] == '!' {
+				n = 2
 			}
+			brackets = append(brackets, &bracket{
+				index:      len(inlines),
+				image:      n == 2,
+				active:     true,
+				start:      i + n,
+				delimiters: len(delimiters),
+			})
+			inlines = append(inlines, &Text{Content: text[i : i+n]})
+			i += n
+			continue
 		}
 
-		// Parse inline code
-		if strings.HasPrefix(text[i:], "`") {
+		if text[i] == ']' && len(brackets) > 0 && !brackets[len(brackets)-1].active {
+			// An inactive bracket can no longer open a link.
+			brackets = brackets[:len(brackets)-1]
+			currentText.WriteByte(']')
+			i++
+			continue
+		}
+
+		if text[i] == ']' && len(brackets) > 0 {
 			if currentText.Len() > 0 {
 				inlines = append(inlines, &Text{Content: currentText.String()})
 				currentText.Reset()
 			}
 
-			end := strings.Index(text[i+1:], "`")
-			if end != -1 {
-				codeText := text[i+1 : i+1+end]
-				inlines = append(inlines, &CodeInline{Content: codeText})
-				i += (1 + end + 1)
-				continue
-			} else {
-				currentText.WriteString("`")
-				i++
+			opener := brackets[len(brackets)-1]
+			brackets = brackets[:len(brackets)-1]
+			children := append([]Inline(nil), inlines[opener.index+1:]...)
+
+			node, n, ok := p.matchBracket(text, i, opener)
+			_, isLink := node.(*Link)
+			if isLink {
+				unlinkify(children, linkified)
+			}
+			if ok && !(isLink && containsLink(children)) {
+				closeBracket(node, children, delimiters[opener.delimiters:])
+				delimiters = delimiters[:opener.delimiters]
+				inlines = append(inlines[:opener.index], node)
+
+				// Links cannot contain other links, so the brackets
+				// before a link can no longer open one.
+				if isLink {
+					for _, b := range brackets {
+						if !b.image {
+							b.active = false
+						}
+					}
+				}
+				i += 1 + n
 				continue
 			}
+
+			currentText.WriteByte(']')
+			i++
+			continue
 		}
 
-		// Parse image
-		if strings.HasPrefix(text[i:], "!") {
+		// Code spans open and close with backtick strings of equal length
+		if text[i] == '`' {
+			run := backtickRun(text[i:])
+			end := backticks.findClosing(text, i+run, run)
+			if end < 0 {
+				currentText.WriteString(text[i : i+run])
+				i += run
+				continue
+			}
+
 			if currentText.Len() > 0 {
 				inlines = append(inlines, &Text{Content: currentText.String()})
 				currentText.Reset()
 			}
 
-			if strings.HasPrefix(text[i+1:], "[") {
-				end := strings.Index(text[i+2:], "]")
-				if end != -1 && strings.HasPrefix(text[i+2+end+1:], "(") {
-					altText := text[i+2 : i+2+end]
-
-					urlStart := i + 2 + end + 2
-					urlAndTitle := text[urlStart:]
-					urlAndTitleEnd := strings.Index(urlAndTitle, ")")
-
-					if urlAndTitleEnd != -1 {
-						urlAndTitle = urlAndTitle[:urlAndTitleEnd]
-
-						titleMatch := regexp.MustCompile(`^(.*?)\s+(.*?)$`).FindStringSubmatch(urlAndTitle)
-
-						var linkURL, title string
-
-						if titleMatch != nil {
-							linkURL = titleMatch[1]
-							title = titleMatch[2]
-
-							if strings.HasPrefix(title, "\"") && strings.HasSuffix(title, "\"") {
-								title = title[1 : len(title)-1]
-							}
-						} else {
-							linkURL = urlAndTitle
-						}
-
-						inlines = append(inlines,
-							&Image{
-								Alt:   altText,
-								Src:   linkURL,
-								Title: title,
-							},
-						)
+			code := &CodeInline{Content: codeSpanContent(text[i+run : i+run+end])}
+			i += run + end + run
+			if p.opts.Attributes {
+				if attributes, n, ok := parseAttributes(text[i:]); ok {
+					code.Attributes = attributes
+					i += n
+				}
+			}
+			inlines = append(inlines, code)
+			continue
+		}
 
-						i += (2 + end + 2 + urlAndTitleEnd + 1)
-						continue
-					}
+		// Autolinks and raw HTML between pointy brackets
+		if text[i] == '<' {
+			if link, n, ok := parseAutolink(text[i:]); ok {
+				if currentText.Len() > 0 {
+					inlines = append(inlines, &Text{Content: currentText.String()})
+					currentText.Reset()
+				}
+				inlines = append(inlines, link)
+				i += n
+				continue
+			}
+			if html, n, ok := parseRawHTML(text[i:], missing); ok {
+				if currentText.Len() > 0 {
+					inlines = append(inlines, &Text{Content: currentText.String()})
+					currentText.Reset()
+				}
+				inlines = append(inlines, html)
+				i += n
+				continue
+			}
+		}
 
+		// Bare URLs, when linkify is on
+		if p.opts.Linkify && (text[i] == 'h' || text[i] == 'w') {
+			if link, n, ok := linkify(text, i); ok {
+				if currentText.Len() > 0 {
+					inlines = append(inlines, &Text{Content: currentText.String()})
+					currentText.Reset()
 				}
+				inlines = append(inlines, link)
+				linkified[link] = true
+				i += n
+				continue
 			}
 		}
 
@@ -320,19 +665,65 @@ func (p *parser) parseInline(text string) []Inline {
 		inlines = append(inlines, &Text{Content: currentText.String()})
 	}
 
-	return inlines
+	return processEmphasis(inlines, delimiters)
 }
 
+// codeSpanContent normalizes the content of a code span: line endings become
+// spaces, and one space is stripped from each side when both are present,
+// so that a span can begin or end with a backtick.
+func codeSpanContent(code string) string {
+	code = strings.ReplaceAll(code, "\n", " ")
+	if len(code) >= 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+		code = code[1 : len(code)-1]
+	}
+	return code
+}
+
+// Parse parses markdown using DefaultOptions.
 func Parse(markdown string) (*Document, error) {
+	return ParseWithOptions(markdown, DefaultOptions())
+}
+
+// ParseWithOptions parses markdown into a Document using the given dialect.
+func ParseWithOptions(markdown string, opts Options) (*Document, error) {
 	normalizedMarkdown := strings.ReplaceAll(markdown, "\r\n", "\n")
 	if !strings.HasSuffix(normalizedMarkdown, "\n") {
 		normalizedMarkdown += "\n"
 	}
-	p := &parser{
-		input: normalizedMarkdown,
-		pos:   0,
+
+	var meta map[string]any
+	if opts.effective().FrontMatter {
+		meta, normalizedMarkdown = extractFrontMatter(normalizedMarkdown)
+	}
+	// Links can refer to definitions further down the document, so a first
+	// pass collects every definition before the inlines are parsed.
+	references := make(map[string]*LinkReference)
+	footnotes := newFootnoteIndex()
+	collector := &parser{
+		input:       normalizedMarkdown,
+		opts:        opts.effective(),
+		references:  references,
+		footnotes:   footnotes,
+		skipInlines: true,
+	}
+	if _, err := collector.parse(); err != nil {
+		return nil, err
 	}
-	return p.parse()
+
+	p := &parser{
+		input:      normalizedMarkdown,
+		pos:        0,
+		opts:       opts.effective(),
+		references: references,
+		footnotes:  footnotes,
+		headingIDs: make(headingIDs),
+	}
+	doc, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	doc.Meta = meta
+	return doc, nil
 }
 
 func (p *parser) parseTable() (*Table, error) {
@@ -351,13 +742,13 @@ func (p *parser) parseTable() (*Table, error) {
 
 	var rows [][]TableCell
 
-	for p.pos < len(p.input) {
-		p.readLine()
-
+	for {
 		// check for the end of the table
-		if p.line == "" || !strings.Contains(p.line, "|") {
+		next, ok := p.peekLine()
+		if !ok || isBlankLine(next) || !strings.Contains(next, "|") {
 			break
 		}
+		p.readLine()
 
 		row := p.parseTableRow(p.line)
 		if len(row) < len(headerCells) {
@@ -378,15 +769,20 @@ func (p *parser) parseTable() (*Table, error) {
 	}, nil
 }
 
-func (p *parser) parseTableRow(line string) []TableCell {
-	parts := strings.Split(line, "|")
+func splitTableRow(line string) []string {
+	parts := strings.Split(strings.TrimSpace(line), "|")
 
-	if parts[0] == "" {
+	if len(parts) > 0 && parts[0] == "" {
 		parts = parts[1:]
 	}
-	if parts[len(parts)-1] == "" {
+	if len(parts) > 0 && parts[len(parts)-1] == "" {
 		parts = parts[:len(parts)-1]
 	}
+	return parts
+}
+
+func (p *parser) parseTableRow(line string) []TableCell {
+	parts := splitTableRow(line)
 
 	cells := make([]TableCell, len(parts))
 	for i, part := range parts {
@@ -399,14 +795,7 @@ func (p *parser) parseTableRow(line string) []TableCell {
 }
 
 func (p *parser) parseTableAlignments(delimiterLine string) []Alignment {
-	parts := strings.Split(delimiterLine, "|")
-
-	if parts[0] == "" {
-		parts = parts[1:]
-	}
-	if parts[len(parts)-1] == "" {
-		parts = parts[:len(parts)-1]
-	}
+	parts := splitTableRow(delimiterLine)
 
 	alignments := make([]Alignment, len(parts))
 	for i, part := range parts {
@@ -428,165 +817,3 @@ func (p *parser) parseTableAlignments(delimiterLine string) []Alignment {
 	}
 	return alignments
 }
-
-func (p *parser) parseList(line string) (*List, error) {
-	var items []*ListItem
-	var listType ListType
-
-	trimmedLine := strings.TrimLeftFunc(line, unicode.IsSpace)
-	if strings.HasPrefix(trimmedLine, "- ") || strings.HasPrefix(trimmedLine, "* ") {
-		listType = UnorderedList
-	} else {
-		listType = OrderedList
-	}
-
-	for p.pos <= len(p.input) {
-		inlineElements, level, isListItem, itemType := p.parseListItem(p.line)
-
-		if !isListItem {
-			break
-		}
-
-		newItem := &ListItem{
-			Level:   level,
-			Content: inlineElements,
-		}
-
-		if len(items) == 0 || level == 0 {
-			items = append(items, newItem)
-		} else {
-			parent := FindListItemParent(items, level)
-			if parent != nil {
-				if parent.Children == nil {
-					parent.Children = &List{
-						Items: []*ListItem{newItem},
-						Type:  itemType,
-					}
-				} else {
-					parent.Children.Items = append(parent.Children.Items, newItem)
-				}
-			} else {
-				items = append(items, newItem)
-			}
-		}
-
-		if p.pos >= len(p.input) {
-			break
-		}
-		p.readLine()
-
-		if strings.TrimSpace(p.line) == "" {
-			break
-		}
-
-		_, _, nextIsListItem, _ := p.parseListItem(p.line)
-		if !nextIsListItem {
-			break
-		}
-	}
-	return &List{
-		Items: items,
-		Type:  listType,
-	}, nil
-}
-
-func (p *parser) parseListItem(line string) ([]Inline, int, bool, ListType) {
-	trimmedLine := strings.TrimLeftFunc(line, unicode.IsSpace)
-	indentation := len(line) - len(trimmedLine)
-	level := indentation / 2
-
-	if strings.HasPrefix(trimmedLine, "- ") || strings.HasPrefix(trimmedLine, "* ") {
-		text := strings.TrimSpace(trimmedLine[2:])
-		return p.parseInline(text), level, true, UnorderedList
-	}
-
-	if match := regexp.MustCompile(`^\d+\.\s+`).FindString(trimmedLine); match != "" {
-		text := strings.TrimSpace(trimmedLine[len(match):])
-		return p.parseInline(text), level, true, OrderedList
-	}
-
-	return nil, 0, false, 0
-}
-
-func FindListItemParent(items []*ListItem, level int) *ListItem {
-	if len(items) == 0 {
-		return nil
-	}
-
-	for i := len(items) - 1; i >= 0; i-- {
-		item := items[i]
-
-		if item.Level == level-1 {
-			return item
-		}
-
-		if item.Children != nil && item.Level < level {
-			return FindListItemParent(item.Children.Items, level)
-		}
-	}
-
-	return nil
-}
-
-func FindBlockQuoteItemParent(items []*BlockquoteItem, level int) *BlockquoteItem {
-	if len(items) == 0 {
-		return nil
-	}
-
-	for i := len(items) - 1; i >= 0; i-- {
-		item := items[i]
-
-		if item.Level == level-1 {
-			return item
-		}
-
-		if item.Children != nil && item.Level < level {
-			return FindBlockQuoteItemParent(item.Children.Items, level)
-		}
-	}
-	return nil
-}
-
-func (p *parser) parseBlockquote() (*Blockquote, error) {
-	blockquote := &Blockquote{}
-
-	blockquote.Items = append(blockquote.Items, &BlockquoteItem{
-		Content: p.parseInline(p.line[1:]),
-		Level:   1,
-	})
-
-	for p.pos < len(p.input) {
-		p.readLine()
-		trimmedLine := strings.TrimSpace(p.line)
-
-		if strings.HasPrefix(trimmedLine, ">") {
-			level := strings.Count(trimmedLine, ">")
-			trimmedLine = strings.TrimSpace(p.line[2*level-1:])
-			if trimmedLine != "" {
-				newItem := &BlockquoteItem{
-					Content: p.parseInline(trimmedLine),
-					Level:   level,
-				}
-				if level == 1 {
-					blockquote.Items = append(blockquote.Items, newItem)
-				} else {
-					parent := FindBlockQuoteItemParent(blockquote.Items, level)
-					if parent != nil {
-						if parent.Children == nil {
-							parent.Children = &Blockquote{
-								Items: []*BlockquoteItem{newItem},
-							}
-						} else {
-							parent.Children.Items = append(parent.Children.Items, newItem)
-						}
-					}
-				}
-				fmt.Println(level, trimmedLine)
-			}
-
-		} else {
-			break
-		}
-	}
-	return blockquote, nil
-}
@@ -0,0 +1,64 @@
+package parser
+
+import "strings"
+
+// tocPlaceholder matches the text of a paragraph that asks for a table of
+// contents.
+const tocPlaceholder = "[toc]"
+
+// TOCEntry is a heading in a table of contents, with the headings of lower
+// levels that follow it nested as children.
+type TOCEntry struct {
+	Level    int
+	ID       string
+	Title    string
+	Children []*TOCEntry
+}
+
+// TOC builds the table of contents of the document from its top-level
+// headings between minLevel and maxLevel, inclusive. A heading becomes a
+// child of the closest preceding heading of a lower level.
+func (d *Document) TOC(minLevel, maxLevel int) []*TOCEntry {
+	var entries []*TOCEntry
+	var stack []*TOCEntry
+
+	for _, block := range d.Blocks {
+		heading, ok := block.(*Heading)
+		if !ok || heading.Level < minLevel || heading.Level > maxLevel {
+			continue
+		}
+
+		entry := &TOCEntry{
+			Level: heading.Level,
+			ID:    heading.ID,
+			Title: strings.TrimSpace(plainText(heading.Text)),
+		}
+
+		for len(stack) > 0 && stack[len(stack)-1].Level >= entry.Level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			entries = append(entries, entry)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, entry)
+		}
+		stack = append(stack, entry)
+	}
+
+	return entries
+}
+
+// isTOCPlaceholder reports whether the text of a paragraph is "[TOC]".
+func (p *parser) isTOCPlaceholder(text string) bool {
+	return p.opts.TOC && strings.EqualFold(strings.TrimSpace(text), tocPlaceholder)
+}
+
+// isTOCComment reports whether an HTML block is a "<!-- toc -->" comment.
+func (p *parser) isTOCComment(html string) bool {
+	if !p.opts.TOC || !strings.HasPrefix(strings.TrimSpace(html), "<!--") {
+		return false
+	}
+	comment := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(html), "<!--"), "-->")
+	return strings.EqualFold(strings.TrimSpace(comment), "toc")
+}
@@ -0,0 +1,261 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseTOML parses the subset of TOML used for front matter: key/value
+// pairs with bare, quoted and dotted keys, tables and arrays of tables,
+// strings (including multi-line ones), numbers, booleans, dates, arrays
+// spanning lines and inline tables.
+func parseTOML(src string) (map[string]any, error) {
+	root := make(map[string]any)
+	table := root
+	lines := strings.Split(src, "\n")
+
+	for i := 0; i < len(lines); i++ {
+		line := trimComment(lines[i])
+		text := strings.TrimSpace(line)
+		if text == "" {
+			continue
+		}
+		lineErr := func(err error) error {
+			return fmt.Errorf("toml line %d: %w", i+1, err)
+		}
+
+		if strings.HasPrefix(text, "[[") && strings.HasSuffix(text, "]]") {
+			keys, err := splitTOMLKey(text[2 : len(text)-2])
+			if err != nil {
+				return nil, lineErr(err)
+			}
+			parent, err := tomlTable(root, keys[:len(keys)-1])
+			if err != nil {
+				return nil, lineErr(err)
+			}
+			last := keys[len(keys)-1]
+			array, _ := parent[last].([]any)
+			table = make(map[string]any)
+			parent[last] = append(array, table)
+			continue
+		}
+
+		if strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]") {
+			keys, err := splitTOMLKey(text[1 : len(text)-1])
+			if err != nil {
+				return nil, lineErr(err)
+			}
+			if table, err = tomlTable(root, keys); err != nil {
+				return nil, lineErr(err)
+			}
+			continue
+		}
+
+		eq := findUnquoted(text, "=")
+		if eq < 0 {
+			return nil, lineErr(fmt.Errorf("expected \"key = value\", got %q", text))
+		}
+		keys, err := splitTOMLKey(text[:eq])
+		if err != nil {
+			return nil, lineErr(err)
+		}
+		value := strings.TrimSpace(text[eq+1:])
+
+		// Multi-line strings and arrays continue on the following lines.
+		for j := i + 1; j < len(lines) && !isCompleteTOMLValue(value); j++ {
+			next := lines[j]
+			if !strings.HasPrefix(value, `"""`) && !strings.HasPrefix(value, "'''") {
+				next = strings.TrimSpace(trimComment(next))
+			}
+			value += "\n" + next
+			i = j
+		}
+
+		parsed, err := parseTOMLValue(value)
+		if err != nil {
+			return nil, lineErr(err)
+		}
+		parent, err := tomlTable(table, keys[:len(keys)-1])
+		if err != nil {
+			return nil, lineErr(err)
+		}
+		parent[keys[len(keys)-1]] = parsed
+	}
+
+	return root, nil
+}
+
+// tomlTable returns the table at the path of keys below root, creating the
+// tables that do not exist yet. A path through an array of tables leads to
+// its last table.
+func tomlTable(root map[string]any, keys []string) (map[string]any, error) {
+	table := root
+	for _, key := range keys {
+		switch next := table[key].(type) {
+		case nil:
+			created := make(map[string]any)
+			table[key] = created
+			table = created
+		case map[string]any:
+			table = next
+		case []any:
+			last, ok := next[len(next)-1].(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("key %q is not a table", key)
+			}
+			table = last
+		default:
+			return nil, fmt.Errorf("key %q is not a table", key)
+		}
+	}
+	return table, nil
+}
+
+// splitTOMLKey splits a dotted key into its parts, unquoting quoted ones.
+func splitTOMLKey(key string) ([]string, error) {
+	parts := splitFlow(key, '.')
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("empty key")
+	}
+	for i, part := range parts {
+		if isFlowOrQuoted(part) {
+			unquoted, err := unquote(part)
+			if err != nil {
+				return nil, err
+			}
+			parts[i] = unquoted
+		} else if part == "" {
+			return nil, fmt.Errorf("empty key in %q", key)
+		}
+	}
+	return parts, nil
+}
+
+// isCompleteTOMLValue reports whether value needs no further lines: its
+// multi-line string is closed and its brackets are balanced.
+func isCompleteTOMLValue(value string) bool {
+	for _, delimiter := range []string{`"""`, "'''"} {
+		if strings.HasPrefix(value, delimiter) {
+			return strings.Contains(value[len(delimiter):], delimiter)
+		}
+	}
+	if !strings.HasPrefix(value, "[") {
+		return true
+	}
+
+	depth := 0
+	var quote byte
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		}
+	}
+	return depth <= 0
+}
+
+// unquoteMultiline resolves the escapes of the body of a multi-line basic
+// string, which may hold unescaped quotes and line endings.
+func unquoteMultiline(body string) (string, error) {
+	var quoted strings.Builder
+	quoted.WriteByte('"')
+	for i := 0; i < len(body); i++ {
+		switch c := body[i]; c {
+		case '\\':
+			quoted.WriteByte(c)
+			if i+1 < len(body) {
+				i++
+				quoted.WriteByte(body[i])
+			}
+		case '"':
+			quoted.WriteString(`\"`)
+		case '\n':
+			quoted.WriteString(`\n`)
+		default:
+			quoted.WriteByte(c)
+		}
+	}
+	quoted.WriteByte('"')
+	return unquote(quoted.String())
+}
+
+func parseTOMLValue(s string) (any, error) {
+	switch {
+	case strings.HasPrefix(s, `"""`) || strings.HasPrefix(s, "'''"):
+		delimiter := s[:3]
+		body := strings.TrimSuffix(s[3:], delimiter)
+		// A line ending right after the opening delimiter is trimmed.
+		body = strings.TrimPrefix(body, "\n")
+		if delimiter == "'''" {
+			return body, nil
+		}
+		return unquoteMultiline(body)
+
+	case strings.HasPrefix(s, `"`) || strings.HasPrefix(s, "'"):
+		return unquote(s)
+
+	case strings.HasPrefix(s, "["):
+		if !strings.HasSuffix(s, "]") {
+			return nil, fmt.Errorf("unterminated array %s", s)
+		}
+		array := []any{}
+		for _, item := range splitFlow(strings.ReplaceAll(s[1:len(s)-1], "\n", " "), ',') {
+			value, err := parseTOMLValue(item)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		return array, nil
+
+	case strings.HasPrefix(s, "{"):
+		if !strings.HasSuffix(s, "}") {
+			return nil, fmt.Errorf("unterminated inline table %s", s)
+		}
+		table := make(map[string]any)
+		for _, item := range splitFlow(s[1:len(s)-1], ',') {
+			eq := findUnquoted(item, "=")
+			if eq < 0 {
+				return nil, fmt.Errorf("expected \"key = value\", got %q", item)
+			}
+			keys, err := splitTOMLKey(item[:eq])
+			if err != nil {
+				return nil, err
+			}
+			value, err := parseTOMLValue(strings.TrimSpace(item[eq+1:]))
+			if err != nil {
+				return nil, err
+			}
+			parent, err := tomlTable(table, keys[:len(keys)-1])
+			if err != nil {
+				return nil, err
+			}
+			parent[keys[len(keys)-1]] = value
+		}
+		return table, nil
+
+	case s == "true":
+		return true, nil
+	case s == "false":
+		return false, nil
+	}
+
+	if n, ok := parseNumber(strings.ReplaceAll(s, "_", "")); ok {
+		return n, nil
+	}
+	if t, ok := parseDate(s); ok {
+		return t, nil
+	}
+	return nil, fmt.Errorf("invalid value %q", s)
+}
@@ -0,0 +1,313 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// yamlParser parses the subset of YAML used for front matter: nested
+// mappings and sequences in block style, flow sequences and mappings,
+// quoted and plain scalars, literal and folded block scalars, and comments.
+// Anchors, tags and multi-document streams are not supported.
+type yamlParser struct {
+	lines []string
+	i     int
+}
+
+func parseYAML(src string) (map[string]any, error) {
+	y := &yamlParser{lines: strings.Split(strings.TrimRight(src, "\n"), "\n")}
+	y.skipEmpty()
+	if y.done() {
+		return map[string]any{}, nil
+	}
+
+	value, err := y.parseNode(indentOf(y.lines[y.i]))
+	if err != nil {
+		return nil, err
+	}
+	if !y.done() {
+		return nil, y.errorf("unexpected indentation")
+	}
+
+	meta, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("yaml front matter must be a mapping")
+	}
+	return meta, nil
+}
+
+func (y *yamlParser) done() bool {
+	return y.i >= len(y.lines)
+}
+
+// skipEmpty moves past blank and comment-only lines.
+func (y *yamlParser) skipEmpty() {
+	for !y.done() && trimComment(y.lines[y.i]) == "" {
+		y.i++
+	}
+}
+
+func (y *yamlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("yaml line %d: %s", y.i+1, fmt.Sprintf(format, args...))
+}
+
+// current returns the current line without its comment and indentation.
+func (y *yamlParser) current() string {
+	return strings.TrimSpace(trimComment(y.lines[y.i]))
+}
+
+func isSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// parseNode parses the block mapping or sequence whose lines are indented by
+// indent.
+func (y *yamlParser) parseNode(indent int) (any, error) {
+	if isSequenceItem(y.current()) {
+		return y.parseSequence(indent)
+	}
+	return y.parseMapping(indent)
+}
+
+func (y *yamlParser) parseMapping(indent int) (map[string]any, error) {
+	mapping := make(map[string]any)
+
+	for y.skipEmpty(); !y.done(); y.skipEmpty() {
+		lineIndent := indentOf(y.lines[y.i])
+		if lineIndent < indent {
+			break
+		}
+		if lineIndent > indent {
+			return nil, y.errorf("unexpected indentation")
+		}
+
+		text := y.current()
+		if isSequenceItem(text) {
+			break
+		}
+
+		key, value, err := y.splitKey(text)
+		if err != nil {
+			return nil, err
+		}
+		y.i++
+
+		mapping[key], err = y.parseValue(value, indent, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return mapping, nil
+}
+
+func (y *yamlParser) parseSequence(indent int) ([]any, error) {
+	sequence := []any{}
+
+	for y.skipEmpty(); !y.done(); y.skipEmpty() {
+		lineIndent := indentOf(y.lines[y.i])
+		text := y.current()
+		if lineIndent != indent || !isSequenceItem(text) {
+			if lineIndent > indent {
+				return nil, y.errorf("unexpected indentation")
+			}
+			break
+		}
+
+		item := strings.TrimSpace(strings.TrimPrefix(text, "-"))
+
+		// An item that starts a mapping, "- key: value", is parsed as a
+		// mapping indented to the column of its first key.
+		if _, _, err := y.splitKey(item); err == nil && !isFlowOrQuoted(item) {
+			column := lineIndent + strings.Index(y.lines[y.i][lineIndent:], item)
+			y.lines[y.i] = strings.Repeat(" ", column) + item
+			value, err := y.parseMapping(column)
+			if err != nil {
+				return nil, err
+			}
+			sequence = append(sequence, value)
+			continue
+		}
+
+		y.i++
+		value, err := y.parseValue(item, indent, false)
+		if err != nil {
+			return nil, err
+		}
+		sequence = append(sequence, value)
+	}
+
+	return sequence, nil
+}
+
+// parseValue parses the value that follows a key or a sequence dash on a
+// line indented by indent. An empty value introduces a nested block on the
+// following lines; a sequence may also be nested at the key's own
+// indentation.
+func (y *yamlParser) parseValue(value string, indent int, isKey bool) (any, error) {
+	switch {
+	case value == "|" || value == "|-" || value == "|+" || value == ">" || value == ">-" || value == ">+":
+		return y.parseBlockScalar(value, indent), nil
+
+	case value == "":
+		y.skipEmpty()
+		if y.done() {
+			return nil, nil
+		}
+		nextIndent := indentOf(y.lines[y.i])
+		if nextIndent > indent || isKey && nextIndent == indent && isSequenceItem(y.current()) {
+			return y.parseNode(nextIndent)
+		}
+		return nil, nil
+	}
+
+	scalar, err := parseYAMLScalar(value)
+	if err != nil {
+		// The value's line has already been consumed.
+		return nil, fmt.Errorf("yaml line %d: %w", y.i, err)
+	}
+	return scalar, nil
+}
+
+// parseBlockScalar reads a literal ("|") or folded (">") block scalar from
+// the lines indented past indent.
+func (y *yamlParser) parseBlockScalar(style string, indent int) string {
+	var lines []string
+	blockIndent := -1
+
+	for ; !y.done(); y.i++ {
+		line := y.lines[y.i]
+		if strings.TrimSpace(line) == "" {
+			lines = append(lines, "")
+			continue
+		}
+		lineIndent := indentOf(line)
+		if lineIndent <= indent {
+			break
+		}
+		if blockIndent < 0 {
+			blockIndent = lineIndent
+		}
+		lines = append(lines, line[min(blockIndent, lineIndent):])
+	}
+
+	// Trailing blank lines belong to whatever follows unless kept.
+	content := strings.TrimRight(strings.Join(lines, "\n"), "\n")
+	if strings.HasPrefix(style, ">") {
+		content = foldLines(content)
+	}
+	switch {
+	case strings.HasSuffix(style, "-"):
+		return content
+	case strings.HasSuffix(style, "+"):
+		return strings.Join(lines, "\n") + "\n"
+	}
+	return content + "\n"
+}
+
+// foldLines joins the lines of a folded block scalar with spaces, keeping
+// blank lines as line breaks.
+func foldLines(content string) string {
+	paragraphs := strings.Split(content, "\n\n")
+	for i, paragraph := range paragraphs {
+		paragraphs[i] = strings.ReplaceAll(paragraph, "\n", " ")
+	}
+	return strings.Join(paragraphs, "\n")
+}
+
+// splitKey splits "key: value" into its key and value.
+func (y *yamlParser) splitKey(text string) (string, string, error) {
+	i := findUnquoted(text+" ", ": ")
+	if i < 0 {
+		return "", "", y.errorf("expected \"key: value\", got %q", text)
+	}
+
+	key := strings.TrimSpace(text[:i])
+	if strings.HasPrefix(key, "\"") || strings.HasPrefix(key, "'") {
+		unquoted, err := unquoteYAML(key)
+		if err != nil {
+			return "", "", y.errorf("%v", err)
+		}
+		key = unquoted
+	}
+	return key, strings.TrimSpace(text[min(i+2, len(text)):]), nil
+}
+
+func isFlowOrQuoted(s string) bool {
+	return s != "" && strings.IndexByte("[{\"'", s[0]) >= 0
+}
+
+// parseYAMLScalar parses a scalar or a flow collection.
+func parseYAMLScalar(s string) (any, error) {
+	switch {
+	case strings.HasPrefix(s, "["):
+		if !strings.HasSuffix(s, "]") {
+			return nil, fmt.Errorf("unterminated flow sequence %s", s)
+		}
+		sequence := []any{}
+		for _, item := range splitFlow(s[1:len(s)-1], ',') {
+			value, err := parseYAMLScalar(item)
+			if err != nil {
+				return nil, err
+			}
+			sequence = append(sequence, value)
+		}
+		return sequence, nil
+
+	case strings.HasPrefix(s, "{"):
+		if !strings.HasSuffix(s, "}") {
+			return nil, fmt.Errorf("unterminated flow mapping %s", s)
+		}
+		mapping := make(map[string]any)
+		for _, item := range splitFlow(s[1:len(s)-1], ',') {
+			i := findUnquoted(item+" ", ": ")
+			if i < 0 {
+				return nil, fmt.Errorf("expected \"key: value\", got %q", item)
+			}
+			value, err := parseYAMLScalar(strings.TrimSpace(item[min(i+2, len(item)):]))
+			if err != nil {
+				return nil, err
+			}
+			key := strings.TrimSpace(item[:i])
+			if isFlowOrQuoted(key) {
+				if key, err = unquoteYAML(key); err != nil {
+					return nil, err
+				}
+			}
+			mapping[key] = value
+		}
+		return mapping, nil
+
+	case strings.HasPrefix(s, "\"") || strings.HasPrefix(s, "'"):
+		return unquoteYAML(s)
+	}
+
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	}
+	if n, ok := parseNumber(s); ok {
+		return n, nil
+	}
+	if t, ok := parseDate(s); ok {
+		return t, nil
+	}
+	return s, nil
+}
+
+// unquoteYAML unquotes a YAML string. Single-quoted strings escape a quote
+// by doubling it.
+func unquoteYAML(s string) (string, error) {
+	unquoted, err := unquote(s)
+	if err != nil {
+		return "", err
+	}
+	if s[0] == '\'' {
+		unquoted = strings.ReplaceAll(unquoted, "''", "'")
+	}
+	return unquoted, nil
+}
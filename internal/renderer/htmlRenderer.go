@@ -3,9 +3,11 @@ package renderer
 import (
 	"bytes"
 	"fmt"
-	"html"
 	"log"
 	"os"
+	"slices"
+	"sort"
+	"strings"
 
 	"github.com/shonnnoronha/madopa/internal/parser"
 )
@@ -15,9 +17,50 @@ const (
 	defaultScriptFilePath = "./internal/renderer/scripts/highlight.html"
 )
 
+// htmlEscaper escapes the characters CommonMark requires to be escaped in
+// text and attribute values. Unlike html.EscapeString it leaves single quotes
+// alone and uses named references, matching the reference implementations.
+var htmlEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	"\"", "&quot;",
+)
+
+func escapeHTML(s string) string {
+	return htmlEscaper.Replace(s)
+}
+
+// normalizeURL percent-encodes the characters of a link destination that are
+// not allowed to appear unescaped in a URL. Existing percent-encoded
+// sequences are kept as they are.
+func normalizeURL(url string) string {
+	var b strings.Builder
+	for i := 0; i < len(url); i++ {
+		c := url[i]
+		switch {
+		case c == '%' && i+2 < len(url) && isHexDigit(url[i+1]) && isHexDigit(url[i+2]):
+			b.WriteByte(c)
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+			b.WriteByte(c)
+		case strings.IndexByte(";/?:@&=+$,-_.!~*'()#", c) >= 0:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func isHexDigit(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
 type HTMLRenderer struct {
 	buffer *bytes.Buffer
 	opts   *Options
+	// toc is the table of contents of the document being rendered.
+	toc []*parser.TOCEntry
 }
 
 func NewHTMLRenderer(opts *Options) *HTMLRenderer {
@@ -29,6 +72,7 @@ func NewHTMLRenderer(opts *Options) *HTMLRenderer {
 
 func (r *HTMLRenderer) Render(doc *parser.Document) (string, error) {
 	r.buffer.Reset()
+	r.toc = doc.TOC(r.tocLevels())
 
 	if r.opts.IncludeCSS {
 		cssFilePath := r.opts.CssFilePath
@@ -40,7 +84,7 @@ func (r *HTMLRenderer) Render(doc *parser.Document) (string, error) {
 		r.buffer.WriteString("<html>\n<head>\n")
 		r.buffer.WriteString("<meta charset=\"UTF-8\">\n")
 		r.buffer.WriteString("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1.0\">\n")
-		r.buffer.WriteString("<title>Markdown Blog</title>\n")
+		r.buffer.WriteString(fmt.Sprintf("<title>%s</title>\n", escapeHTML(documentTitle(doc))))
 		r.buffer.WriteString("<style>\n")
 
 		cssContent, err := os.ReadFile(cssFilePath)
@@ -58,6 +102,10 @@ func (r *HTMLRenderer) Render(doc *parser.Document) (string, error) {
 		}
 		r.buffer.Write(scriptContent)
 
+		if r.opts.TOCSidebar && len(r.toc) > 0 {
+			r.renderTOC(" class=\"toc-sidebar\"")
+		}
+
 		r.buffer.WriteString("<div class=\"container\">\n")
 		r.buffer.WriteString("<article class=\"post\">\n")
 	}
@@ -68,6 +116,12 @@ func (r *HTMLRenderer) Render(doc *parser.Document) (string, error) {
 		}
 	}
 
+	if len(doc.Footnotes) > 0 {
+		if err := r.renderFootnotes(doc.Footnotes); err != nil {
+			return "", err
+		}
+	}
+
 	if r.opts.IncludeCSS {
 		r.buffer.WriteString("\n</article>\n</div>\n</body>\n</html>")
 	}
@@ -75,38 +129,55 @@ func (r *HTMLRenderer) Render(doc *parser.Document) (string, error) {
 	return r.buffer.String(), nil
 }
 
+// documentTitle returns the title from the document's front matter, or a
+// generic one.
+func documentTitle(doc *parser.Document) string {
+	if title, ok := doc.Meta["title"].(string); ok && strings.TrimSpace(title) != "" {
+		return title
+	}
+	return "Markdown Blog"
+}
+
 func (r *HTMLRenderer) renderBlock(block parser.Block) error {
 	switch b := block.(type) {
 	case *parser.Heading:
 		level := b.Level
-		r.buffer.WriteString(fmt.Sprintf("<h%d>", level))
+		if b.ID != "" {
+			r.buffer.WriteString(fmt.Sprintf("<h%d id=\"%s\"%s>", level, escapeHTML(b.ID), r.attributes(b.Attributes, "")))
+		} else {
+			r.buffer.WriteString(fmt.Sprintf("<h%d%s>", level, r.attributes(b.Attributes, "")))
+		}
 		if err := r.renderInlines(b.Text); err != nil {
 			return err
 		}
+		if b.ID != "" && r.opts.HeadingPermalinks {
+			r.buffer.WriteString(fmt.Sprintf(" <a class=\"heading-permalink\" href=\"#%s\" aria-label=\"Permalink\">#</a>",
+				escapeHTML(normalizeURL(b.ID))))
+		}
 		r.buffer.WriteString(fmt.Sprintf("</h%d>\n", level))
 
 	case *parser.Paragraph:
-		r.buffer.WriteString("<p>")
+		r.buffer.WriteString(fmt.Sprintf("<p%s>", r.attributes(b.Attributes, "")))
 		if err := r.renderInlines(b.Text); err != nil {
 			return err
 		}
 		r.buffer.WriteString("</p>\n")
 
+	case *parser.ThematicBreak:
+		r.buffer.WriteString(fmt.Sprintf("<hr%s />\n", r.attributes(b.Attributes, "")))
+
 	case *parser.CodeBlock:
+		r.buffer.WriteString(fmt.Sprintf("<pre%s>", r.attributes(b.Attributes, "")))
 		if b.Lang != "" {
-			r.buffer.WriteString(fmt.Sprintf("<pre><code class=\"%s\">", b.Lang))
+			r.buffer.WriteString(fmt.Sprintf("<code class=\"language-%s\">", escapeHTML(b.Lang)))
 		} else {
-			r.buffer.WriteString("<pre><code>")
-		}
-		content := b.Code
-		if r.opts.EscapeHTML {
-			content = html.EscapeString(content)
+			r.buffer.WriteString("<code>")
 		}
-		r.buffer.WriteString(content)
+		r.buffer.WriteString(escapeHTML(b.Code))
 		r.buffer.WriteString("</code></pre>\n")
 
 	case *parser.Table:
-		r.buffer.WriteString("<table>\n")
+		r.buffer.WriteString(fmt.Sprintf("<table%s>\n", r.attributes(b.Attributes, "")))
 
 		r.buffer.WriteString("<thead>\n")
 		r.buffer.WriteString("<tr>\n")
@@ -163,13 +234,21 @@ func (r *HTMLRenderer) renderBlock(block parser.Block) error {
 		r.buffer.WriteString("</table>\n")
 
 	case *parser.List:
+		class := ""
+		if isTaskList(b) {
+			class = "task-list"
+		}
 		if b.Type == parser.OrderedList {
-			r.buffer.WriteString("<ol>\n")
+			if b.Start != 1 {
+				r.buffer.WriteString(fmt.Sprintf("<ol start=\"%d\"%s>\n", b.Start, r.attributes(b.Attributes, class, "start")))
+			} else {
+				r.buffer.WriteString(fmt.Sprintf("<ol%s>\n", r.attributes(b.Attributes, class)))
+			}
 		} else {
-			r.buffer.WriteString("<ul>\n")
+			r.buffer.WriteString(fmt.Sprintf("<ul%s>\n", r.attributes(b.Attributes, class)))
 		}
 
-		if err := r.renderListItems(b.Items); err != nil {
+		if err := r.renderListItems(b.Items, b.Tight); err != nil {
 			return err
 		}
 
@@ -180,13 +259,65 @@ func (r *HTMLRenderer) renderBlock(block parser.Block) error {
 		}
 
 	case *parser.Blockquote:
-		r.buffer.WriteString("<blockquote>")
+		r.buffer.WriteString(fmt.Sprintf("<blockquote%s>\n", r.attributes(b.Attributes, "")))
+
+		for _, child := range b.Children {
+			if err := r.renderBlock(child); err != nil {
+				return err
+			}
+		}
+
+		r.buffer.WriteString("</blockquote>\n")
+
+	case *parser.DefinitionList:
+		r.buffer.WriteString(fmt.Sprintf("<dl%s>\n", r.attributes(b.Attributes, "")))
+		for _, item := range b.Items {
+			if err := r.renderBlock(item); err != nil {
+				return err
+			}
+		}
+		r.buffer.WriteString("</dl>\n")
+
+	case *parser.DefinitionTerm:
+		r.buffer.WriteString(fmt.Sprintf("<dt%s>", r.attributes(b.Attributes, "")))
+		if err := r.renderInlines(b.Text); err != nil {
+			return err
+		}
+		r.buffer.WriteString("</dt>\n")
+
+	case *parser.DefinitionDescription:
+		r.buffer.WriteString(fmt.Sprintf("<dd%s>", r.attributes(b.Attributes, "")))
+		for _, child := range b.Children {
+			// Paragraphs of tight definitions are rendered without <p>
+			// tags, like those of tight lists.
+			if paragraph, ok := child.(*parser.Paragraph); ok && b.Tight && len(paragraph.Attributes) == 0 {
+				if err := r.renderInlines(paragraph.Text); err != nil {
+					return err
+				}
+				continue
+			}
+
+			r.newline()
+			if err := r.renderBlock(child); err != nil {
+				return err
+			}
+		}
+		r.buffer.WriteString("</dd>\n")
 
-		if err := r.renderBlockQuoteItems(b.Items); err != nil {
+	case *parser.MathBlock:
+		r.buffer.WriteString(latexToMathML(b.Content, true, r.attributes(b.Attributes, "", "xmlns", "display")))
+		r.buffer.WriteString("\n")
+
+	case *parser.Admonition:
+		if err := r.renderAdmonition(b); err != nil {
 			return err
 		}
 
-		r.buffer.WriteString("</blockquote>")
+	case *parser.TOCPlaceholder:
+		r.renderTOC(r.attributes(b.Attributes, "toc"))
+
+	case *parser.HTMLBlock:
+		r.buffer.WriteString(r.rawHTML(b.Content))
 
 	default:
 		r.buffer.WriteString(fmt.Sprintf("<!-- Unsupported block type: %T -->\n", b))
@@ -199,75 +330,122 @@ func (r *HTMLRenderer) renderInlines(inlines []parser.Inline) error {
 	for _, inline := range inlines {
 		switch i := inline.(type) {
 		case *parser.Text:
-			content := i.Content
-			if r.opts.EscapeHTML {
-				content = html.EscapeString(content)
+			r.buffer.WriteString(escapeHTML(i.Content))
+
+		case *parser.SoftBreak:
+			if r.opts.SoftLineBreak {
+				r.buffer.WriteString("<br />\n")
+			} else {
+				r.buffer.WriteString("\n")
+			}
+
+		case *parser.HardBreak:
+			if r.opts.HardLineBreak {
+				r.buffer.WriteString("<br />\n")
+			} else {
+				r.buffer.WriteString("\n")
 			}
-			r.buffer.WriteString(content)
 
 		case *parser.BoldItalic:
-			r.buffer.WriteString("<strong><em>")
+			r.buffer.WriteString(fmt.Sprintf("<em%s><strong>", r.attributes(i.Attributes, "")))
 			if err := r.renderInlines(i.Content); err != nil {
 				return err
 			}
-			r.buffer.WriteString("</em></strong>")
+			r.buffer.WriteString("</strong></em>")
 
 		case *parser.Bold:
-			r.buffer.WriteString("<strong>")
+			r.buffer.WriteString(fmt.Sprintf("<strong%s>", r.attributes(i.Attributes, "")))
 			if err := r.renderInlines(i.Content); err != nil {
 				return err
 			}
 			r.buffer.WriteString("</strong>")
 
 		case *parser.Italic:
-			r.buffer.WriteString("<em>")
+			r.buffer.WriteString(fmt.Sprintf("<em%s>", r.attributes(i.Attributes, "")))
 			if err := r.renderInlines(i.Content); err != nil {
 				return err
 			}
 			r.buffer.WriteString("</em>")
 
+		case *parser.Strikethrough:
+			r.buffer.WriteString(fmt.Sprintf("<del%s>", r.attributes(i.Attributes, "")))
+			if err := r.renderInlines(i.Content); err != nil {
+				return err
+			}
+			r.buffer.WriteString("</del>")
+
+		case *parser.Highlight:
+			r.buffer.WriteString(fmt.Sprintf("<mark%s>", r.attributes(i.Attributes, "")))
+			if err := r.renderInlines(i.Content); err != nil {
+				return err
+			}
+			r.buffer.WriteString("</mark>")
+
+		case *parser.Subscript:
+			r.buffer.WriteString(fmt.Sprintf("<sub%s>", r.attributes(i.Attributes, "")))
+			if err := r.renderInlines(i.Content); err != nil {
+				return err
+			}
+			r.buffer.WriteString("</sub>")
+
+		case *parser.Superscript:
+			r.buffer.WriteString(fmt.Sprintf("<sup%s>", r.attributes(i.Attributes, "")))
+			if err := r.renderInlines(i.Content); err != nil {
+				return err
+			}
+			r.buffer.WriteString("</sup>")
+
 		case *parser.Link:
 			r.buffer.WriteString("<a href=\"")
-			r.buffer.WriteString(i.URL)
-			r.buffer.WriteString("\">")
+			r.buffer.WriteString(r.url(i.URL))
+			r.buffer.WriteString("\"")
+			if i.Title != "" {
+				r.buffer.WriteString(" title=\"")
+				r.buffer.WriteString(escapeHTML(i.Title))
+				r.buffer.WriteString("\"")
+			}
+			r.buffer.WriteString(r.attributes(i.Attributes, "", "href", "title"))
+			r.buffer.WriteString(">")
 			if err := r.renderInlines(i.Text); err != nil {
 				return err
 			}
 			r.buffer.WriteString("</a>")
 
 		case *parser.CodeInline:
-			r.buffer.WriteString("<code>")
-			content := i.Content
-			if r.opts.EscapeHTML {
-				content = html.EscapeString(content)
-			}
-			r.buffer.WriteString(content)
+			r.buffer.WriteString(fmt.Sprintf("<code%s>", r.attributes(i.Attributes, "")))
+			r.buffer.WriteString(escapeHTML(i.Content))
 			r.buffer.WriteString("</code>")
 
+		case *parser.FootnoteReference:
+			r.buffer.WriteString(fmt.Sprintf("<sup%s><a href=\"#fn-%d\" id=\"%s\">%d</a></sup>",
+				r.attributes(i.Attributes, "footnote-ref"), i.Number, footnoteRefID(i.Number, i.Index), i.Number))
+
+		case *parser.MathInline:
+			r.buffer.WriteString(latexToMathML(i.Content, i.Display, r.attributes(i.Attributes, "", "xmlns", "display")))
+
+		case *parser.Span:
+			r.buffer.WriteString(fmt.Sprintf("<span%s>", r.attributes(i.Attributes, "")))
+			if err := r.renderInlines(i.Content); err != nil {
+				return err
+			}
+			r.buffer.WriteString("</span>")
+
+		case *parser.RawHTML:
+			r.buffer.WriteString(r.rawHTML(i.Content))
+
 		case *parser.Image:
 			r.buffer.WriteString("<img src=\"")
-			if r.opts.EscapeHTML {
-				r.buffer.WriteString(html.EscapeString(i.Src))
-			} else {
-				r.buffer.WriteString(i.Src)
-			}
+			r.buffer.WriteString(r.url(i.Src))
 			r.buffer.WriteString("\" alt=\"")
-			if r.opts.EscapeHTML {
-				r.buffer.WriteString(html.EscapeString(i.Alt))
-			} else {
-				r.buffer.WriteString(i.Alt)
-			}
+			r.buffer.WriteString(escapeHTML(i.Alt))
 			r.buffer.WriteString("\"")
 			if i.Title != "" {
-				r.buffer.WriteString("\" title=\"")
-				if r.opts.EscapeHTML {
-					r.buffer.WriteString(html.EscapeString(i.Title))
-				} else {
-					r.buffer.WriteString(i.Title)
-				}
+				r.buffer.WriteString(" title=\"")
+				r.buffer.WriteString(escapeHTML(i.Title))
 				r.buffer.WriteString("\"")
 			}
-			r.buffer.WriteString(">")
+			r.buffer.WriteString(r.attributes(i.Attributes, "", "src", "alt", "title"))
+			r.buffer.WriteString(" />")
 
 		default:
 			r.buffer.WriteString(fmt.Sprintf("<!-- Unsupported inline type: %T -->", i))
@@ -276,49 +454,305 @@ func (r *HTMLRenderer) renderInlines(inlines []parser.Inline) error {
 	return nil
 }
 
-func (r *HTMLRenderer) renderListItems(items []*parser.ListItem) error {
-	for _, item := range items {
+// attributes renders the attributes of a node, with class holding the
+// element's own classes: the id first, then the classes, then the rest in
+// name order. Values are always escaped, and event handlers such as onclick
+// are dropped unless UnsafeHTML is set, so an attribute list cannot run
+// script where raw HTML could not. The reserved names are those the element
+// already writes itself, such as href or src; the element's own values win.
+func (r *HTMLRenderer) attributes(attributes parser.Attributes, class string, reserved ...string) string {
+	var b strings.Builder
+	if id := attributes["id"]; id != "" {
+		b.WriteString(fmt.Sprintf(" id=\"%s\"", escapeHTML(id)))
+	}
+	if extra := attributes["class"]; extra != "" {
+		class = strings.TrimSpace(class + " " + extra)
+	}
+	if class != "" {
+		b.WriteString(fmt.Sprintf(" class=\"%s\"", escapeHTML(class)))
+	}
+
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		if name == "id" || name == "class" || slices.Contains(reserved, strings.ToLower(name)) ||
+			!r.opts.UnsafeHTML && strings.HasPrefix(strings.ToLower(name), "on") {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b.WriteString(fmt.Sprintf(" %s=\"%s\"", name, escapeHTML(attributes[name])))
+	}
+	return b.String()
+}
+
+// rawHTML returns raw HTML from the document as it should be written out:
+// verbatim when the UnsafeHTML option is set, escaped otherwise.
+func (r *HTMLRenderer) rawHTML(html string) string {
+	if r.opts.UnsafeHTML {
+		return html
+	}
+	return escapeHTML(html)
+}
+
+// escapeURL normalizes a link destination and escapes it for use in an
+// attribute.
+func escapeURL(url string) string {
+	return escapeHTML(normalizeURL(url))
+}
+
+// url returns a link or image destination as it should be written out.
+// Unless UnsafeHTML is set, destinations that could run script, such as
+// "javascript:" URLs, are dropped, as in cmark.
+func (r *HTMLRenderer) url(url string) string {
+	if !r.opts.UnsafeHTML && isUnsafeURL(url) {
+		return ""
+	}
+	return escapeURL(url)
+}
+
+// safeDataURLs are the "data:" URL prefixes of image types, which cannot
+// run script.
+var safeDataURLs = []string{"data:image/png", "data:image/gif", "data:image/jpeg", "data:image/webp"}
+
+// isUnsafeURL reports whether url uses the "javascript:", "vbscript:" or
+// "file:" scheme, or is a "data:" URL of a type other than an image.
+func isUnsafeURL(url string) bool {
+	url = strings.ToLower(url)
+	for _, prefix := range safeDataURLs {
+		if strings.HasPrefix(url, prefix) {
+			return false
+		}
+	}
+	for _, scheme := range []string{"javascript:", "vbscript:", "file:", "data:"} {
+		if strings.HasPrefix(url, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *HTMLRenderer) tocLevels() (int, int) {
+	minLevel, maxLevel := r.opts.TOCMinLevel, r.opts.TOCMaxLevel
+	if minLevel == 0 {
+		minLevel = 1
+	}
+	if maxLevel == 0 {
+		maxLevel = 6
+	}
+	return minLevel, maxLevel
+}
+
+// renderTOC renders the table of contents as nested lists in a <nav> with
+// the given attributes.
+func (r *HTMLRenderer) renderTOC(attributes string) {
+	r.buffer.WriteString(fmt.Sprintf("<nav%s>\n", attributes))
+	r.renderTOCEntries(r.toc)
+	r.buffer.WriteString("</nav>\n")
+}
+
+func (r *HTMLRenderer) renderTOCEntries(entries []*parser.TOCEntry) {
+	if len(entries) == 0 {
+		return
+	}
+	r.buffer.WriteString("<ul>\n")
+	for _, entry := range entries {
 		r.buffer.WriteString("<li>")
-		if err := r.renderInlines(item.Content); err != nil {
+		if entry.ID != "" {
+			r.buffer.WriteString(fmt.Sprintf("<a href=\"#%s\">%s</a>", escapeHTML(normalizeURL(entry.ID)), escapeHTML(entry.Title)))
+		} else {
+			r.buffer.WriteString(escapeHTML(entry.Title))
+		}
+		if len(entry.Children) > 0 {
+			r.buffer.WriteString("\n")
+			r.renderTOCEntries(entry.Children)
+		}
+		r.buffer.WriteString("</li>\n")
+	}
+	r.buffer.WriteString("</ul>\n")
+}
+
+// admonitionIcons holds the icon shown in the title of each kind of
+// admonition. Other kinds use the note icon.
+var admonitionIcons = map[string]string{
+	"note":      "ℹ️",
+	"info":      "ℹ️",
+	"tip":       "💡",
+	"important": "❗",
+	"warning":   "⚠️",
+	"caution":   "🛑",
+	"danger":    "🛑",
+}
+
+// renderAdmonition renders an admonition as a div classed by its kind, with
+// a title that defaults to the capitalised kind.
+func (r *HTMLRenderer) renderAdmonition(admonition *parser.Admonition) error {
+	icon, ok := admonitionIcons[admonition.Kind]
+	if !ok {
+		icon = admonitionIcons["note"]
+	}
+	title := admonition.Title
+	if title == "" {
+		title = strings.ToUpper(admonition.Kind[:1]) + admonition.Kind[1:]
+	}
+
+	r.buffer.WriteString(fmt.Sprintf("<div%s>\n", r.attributes(admonition.Attributes, "admonition admonition-"+admonition.Kind)))
+	r.buffer.WriteString(fmt.Sprintf("<p class=\"admonition-title\"><span class=\"admonition-icon\" aria-hidden=\"true\">%s</span>%s</p>\n",
+		icon, escapeHTML(title)))
+
+	for _, child := range admonition.Children {
+		if err := r.renderBlock(child); err != nil {
 			return err
 		}
-		if item.Children != nil {
-			if item.Children.Type == parser.OrderedList {
-				r.buffer.WriteString("\n<ol>\n")
-			} else {
-				r.buffer.WriteString("\n<ul>\n")
+	}
+
+	r.buffer.WriteString("</div>\n")
+	return nil
+}
+
+// renderFootnotes renders the footnote section that ends the document. Each
+// footnote links back to every reference to it, from the end of its last
+// paragraph when it has one.
+func (r *HTMLRenderer) renderFootnotes(footnotes []*parser.FootnoteDefinition) error {
+	r.buffer.WriteString("<section class=\"footnotes\">\n<ol>\n")
+
+	for _, footnote := range footnotes {
+		r.buffer.WriteString(fmt.Sprintf("<li id=\"fn-%d\">\n", footnote.Number))
+
+		children := footnote.Children
+		var last *parser.Paragraph
+		if n := len(children); n > 0 {
+			if paragraph, ok := children[n-1].(*parser.Paragraph); ok {
+				last = paragraph
+				children = children[:n-1]
 			}
+		}
 
-			if err := r.renderListItems(item.Children.Items); err != nil {
+		for _, child := range children {
+			if err := r.renderBlock(child); err != nil {
 				return err
 			}
+		}
 
-			if item.Children.Type == parser.OrderedList {
-				r.buffer.WriteString("</ol>\n")
-			} else {
-				r.buffer.WriteString("</ul>\n")
+		r.buffer.WriteString("<p>")
+		if last != nil {
+			if err := r.renderInlines(last.Text); err != nil {
+				return err
 			}
+			r.buffer.WriteString(" ")
 		}
-		r.buffer.WriteString("</li>\n")
+		r.renderFootnoteBackrefs(footnote)
+		r.buffer.WriteString("</p>\n</li>\n")
 	}
+
+	r.buffer.WriteString("</ol>\n</section>\n")
 	return nil
 }
 
-func (r *HTMLRenderer) renderBlockQuoteItems(items []*parser.BlockquoteItem) error {
+func (r *HTMLRenderer) renderFootnoteBackrefs(footnote *parser.FootnoteDefinition) {
+	for index := 1; index <= footnote.References; index++ {
+		if index > 1 {
+			r.buffer.WriteString(" ")
+		}
+		r.buffer.WriteString(fmt.Sprintf("<a href=\"#%s\" class=\"footnote-backref\" aria-label=\"Back to reference %d\">↩",
+			footnoteRefID(footnote.Number, index), index))
+		if index > 1 {
+			r.buffer.WriteString(fmt.Sprintf("<sup>%d</sup>", index))
+		}
+		r.buffer.WriteString("</a>")
+	}
+}
+
+// footnoteRefID returns the id of the index-th reference to footnote number.
+func footnoteRefID(number, index int) string {
+	if index == 1 {
+		return fmt.Sprintf("fnref-%d", number)
+	}
+	return fmt.Sprintf("fnref-%d-%d", number, index)
+}
+
+func (r *HTMLRenderer) renderListItems(items []*parser.ListItem, tight bool) error {
 	for _, item := range items {
-		err := r.renderInlines(item.Content)
-		r.buffer.WriteString("<br>")
-		if err != nil {
-			return err
+		class := ""
+		if item.Checked != nil {
+			class = "task-list-item"
+		}
+		r.buffer.WriteString(fmt.Sprintf("<li%s>", r.attributes(item.Attributes, class)))
+
+		// The checkbox of a task list item goes in front of the item's
+		// first paragraph, or at the start of the item when its content
+		// opens with another block.
+		if _, ok := firstChild(item.Children).(*parser.Paragraph); !ok && item.Checked != nil {
+			r.renderTaskCheckbox(*item.Checked)
 		}
 
-		if item.Children != nil {
-			r.buffer.WriteString("<blockquote>")
-			if err := r.renderBlockQuoteItems(item.Children.Items); err != nil {
+		for i, child := range item.Children {
+			paragraph, isParagraph := child.(*parser.Paragraph)
+			// Paragraphs of tight lists are rendered without <p> tags,
+			// unless they have attributes to carry.
+			bare := isParagraph && tight && len(paragraph.Attributes) == 0
+
+			if i == 0 && isParagraph && item.Checked != nil {
+				if !bare {
+					r.newline()
+					r.buffer.WriteString(fmt.Sprintf("<p%s>", r.attributes(paragraph.Attributes, "")))
+				}
+				r.renderTaskCheckbox(*item.Checked)
+				if err := r.renderInlines(paragraph.Text); err != nil {
+					return err
+				}
+				if !bare {
+					r.buffer.WriteString("</p>\n")
+				}
+				continue
+			}
+
+			if bare {
+				if err := r.renderInlines(paragraph.Text); err != nil {
+					return err
+				}
+				continue
+			}
+
+			r.newline()
+			if err := r.renderBlock(child); err != nil {
 				return err
 			}
-			r.buffer.WriteString("</blockquote>")
 		}
+		r.buffer.WriteString("</li>\n")
 	}
 	return nil
 }
+
+func firstChild(blocks []parser.Block) parser.Block {
+	if len(blocks) == 0 {
+		return nil
+	}
+	return blocks[0]
+}
+
+// isTaskList reports whether any item of list is a task list item.
+func isTaskList(list *parser.List) bool {
+	for _, item := range list.Items {
+		if item.Checked != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *HTMLRenderer) renderTaskCheckbox(checked bool) {
+	if checked {
+		r.buffer.WriteString("<input type=\"checkbox\" class=\"task-list-item-checkbox\" disabled=\"\" checked=\"\" /> ")
+	} else {
+		r.buffer.WriteString("<input type=\"checkbox\" class=\"task-list-item-checkbox\" disabled=\"\" /> ")
+	}
+}
+
+// newline starts a new line unless the output already ends with one.
+func (r *HTMLRenderer) newline() {
+	if r.buffer.Len() > 0 && r.buffer.Bytes()[r.buffer.Len()-1] != '\n' {
+		r.buffer.WriteByte('\n')
+	}
+}
@@ -0,0 +1,639 @@
+package renderer
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// mathParser converts a LaTeX formula to MathML. It understands the subset
+// that turns up in notes: numbers, letters and operators, sub- and
+// superscripts, groups, Greek letters and common symbols, fractions,
+// binomials, roots, accents, text, font styles, \left and \right fences and
+// the matrix, cases and align environments. Anything else is shown as an
+// error in place of the command.
+type mathParser struct {
+	src     string
+	pos     int
+	display bool
+	// depth counts the atoms being parsed, one inside another.
+	depth int
+}
+
+// maxMathDepth bounds how deeply groups, commands and scripts may nest.
+// Each level copies the markup of the levels inside it, so deeper formulas
+// are shown as an error instead.
+const maxMathDepth = 64
+
+// latexToMathML renders tex as a <math> element with the given attributes,
+// keeping the source as an annotation so that it can be copied back out.
+func latexToMathML(tex string, display bool, attributes string) string {
+	m := &mathParser{src: tex, display: display}
+
+	var b strings.Builder
+	b.WriteString(`<math xmlns="http://www.w3.org/1998/Math/MathML"`)
+	if display {
+		b.WriteString(` display="block"`)
+	}
+	b.WriteString(attributes)
+	b.WriteString("><semantics><mrow>")
+	b.WriteString(m.parseTopLevel())
+	b.WriteString(`</mrow><annotation encoding="application/x-tex">`)
+	b.WriteString(escapeHTML(tex))
+	b.WriteString("</annotation></semantics></math>")
+	return b.String()
+}
+
+var greekLetters = map[string]string{
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ϵ",
+	"varepsilon": "ε", "zeta": "ζ", "eta": "η", "theta": "θ", "vartheta": "ϑ",
+	"iota": "ι", "kappa": "κ", "lambda": "λ", "mu": "μ", "nu": "ν", "xi": "ξ",
+	"omicron": "ο", "pi": "π", "varpi": "ϖ", "rho": "ρ", "varrho": "ϱ",
+	"sigma": "σ", "varsigma": "ς", "tau": "τ", "upsilon": "υ", "phi": "ϕ",
+	"varphi": "φ", "chi": "χ", "psi": "ψ", "omega": "ω",
+}
+
+// upperGreekLetters are upright, unlike other single-letter identifiers.
+var upperGreekLetters = map[string]string{
+	"Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ", "Xi": "Ξ",
+	"Pi": "Π", "Sigma": "Σ", "Upsilon": "Υ", "Phi": "Φ", "Psi": "Ψ",
+	"Omega": "Ω",
+}
+
+var mathIdentifiers = map[string]string{
+	"infty": "∞", "partial": "∂", "nabla": "∇", "emptyset": "∅",
+	"varnothing": "∅", "hbar": "ℏ", "ell": "ℓ", "Re": "ℜ", "Im": "ℑ",
+	"aleph": "ℵ", "angle": "∠", "triangle": "△", "top": "⊤", "bot": "⊥",
+}
+
+var mathOperators = map[string]string{
+	"pm": "±", "mp": "∓", "times": "×", "div": "÷", "cdot": "⋅", "ast": "∗",
+	"star": "⋆", "circ": "∘", "bullet": "∙", "oplus": "⊕", "otimes": "⊗",
+	"leq": "≤", "le": "≤", "geq": "≥", "ge": "≥", "neq": "≠", "ne": "≠",
+	"approx": "≈", "equiv": "≡", "sim": "∼", "simeq": "≃", "cong": "≅",
+	"propto": "∝", "ll": "≪", "gg": "≫", "in": "∈", "notin": "∉", "ni": "∋",
+	"subset": "⊂", "subseteq": "⊆", "supset": "⊃", "supseteq": "⊇",
+	"cup": "∪", "cap": "∩", "setminus": "∖", "wedge": "∧", "land": "∧",
+	"vee": "∨", "lor": "∨", "neg": "¬", "lnot": "¬", "forall": "∀",
+	"exists": "∃", "to": "→", "rightarrow": "→", "leftarrow": "←",
+	"gets": "←", "leftrightarrow": "↔", "Rightarrow": "⇒", "Leftarrow": "⇐",
+	"Leftrightarrow": "⇔", "implies": "⟹", "iff": "⟺", "mapsto": "↦",
+	"uparrow": "↑", "downarrow": "↓", "ldots": "…", "dots": "…",
+	"cdots": "⋯", "vdots": "⋮", "ddots": "⋱", "mid": "∣", "parallel": "∥",
+	"perp": "⊥", "langle": "⟨", "rangle": "⟩", "lfloor": "⌊", "rfloor": "⌋",
+	"lceil": "⌈", "rceil": "⌉", "vert": "|", "Vert": "‖", "lvert": "|",
+	"rvert": "|", "lVert": "‖", "rVert": "‖", "prime": "′", "colon": ":",
+}
+
+// largeOperators take their limits below and above in display math.
+var largeOperators = map[string]string{
+	"sum": "∑", "prod": "∏", "coprod": "∐", "bigcup": "⋃", "bigcap": "⋂",
+	"bigoplus": "⨁", "bigotimes": "⨂", "bigvee": "⋁", "bigwedge": "⋀",
+}
+
+// integrals keep their limits beside them.
+var integrals = map[string]string{
+	"int": "∫", "iint": "∬", "iiint": "∭", "oint": "∮",
+}
+
+// limitFunctions are named functions that, like large operators, take
+// their limits below in display math.
+var limitFunctions = map[string]bool{
+	"lim": true, "limsup": true, "liminf": true, "max": true, "min": true,
+	"sup": true, "inf": true, "det": true, "gcd": true, "Pr": true,
+	"argmax": true, "argmin": true,
+}
+
+var mathFunctions = map[string]bool{
+	"sin": true, "cos": true, "tan": true, "cot": true, "sec": true,
+	"csc": true, "arcsin": true, "arccos": true, "arctan": true,
+	"sinh": true, "cosh": true, "tanh": true, "log": true, "ln": true,
+	"lg": true, "exp": true, "dim": true, "ker": true, "deg": true,
+	"hom": true, "arg": true,
+}
+
+var mathAccents = map[string]string{
+	"hat": "^", "widehat": "^", "bar": "¯", "overline": "‾", "vec": "→",
+	"dot": "˙", "ddot": "¨", "tilde": "~", "widetilde": "~",
+	"overrightarrow": "→",
+}
+
+var mathVariants = map[string]string{
+	"mathbf": "bold", "boldsymbol": "bold-italic", "mathit": "italic",
+	"mathrm": "normal", "mathbb": "double-struck", "mathcal": "script",
+	"mathfrak": "fraktur", "mathsf": "sans-serif", "mathtt": "monospace",
+}
+
+var mathSpaces = map[string]string{
+	",": "0.1667em", ":": "0.2222em", ">": "0.2222em", ";": "0.2778em",
+	"!": "-0.1667em", " ": "0.25em", "quad": "1em", "qquad": "2em",
+}
+
+// matrixFences are the brackets around each matrix environment.
+var matrixFences = map[string][2]string{
+	"matrix": {"", ""}, "smallmatrix": {"", ""}, "pmatrix": {"(", ")"},
+	"bmatrix": {"[", "]"}, "Bmatrix": {"{", "}"}, "vmatrix": {"|", "|"},
+	"Vmatrix": {"‖", "‖"}, "cases": {"{", ""}, "array": {"", ""},
+	"aligned": {"", ""}, "align": {"", ""}, "align*": {"", ""},
+	"gathered": {"", ""}, "split": {"", ""},
+}
+
+// ignoredCommands change spacing or style in ways MathML handles itself.
+var ignoredCommands = map[string]bool{
+	"displaystyle": true, "textstyle": true, "limits": true,
+	"nolimits": true, "nonumber": true,
+}
+
+// parseTopLevel parses the whole formula, keeping whatever stops a row out
+// of place, such as an unmatched "}", as plain operators.
+func (m *mathParser) parseTopLevel() string {
+	var b strings.Builder
+	for {
+		b.WriteString(m.parseRow())
+		if m.done() {
+			return b.String()
+		}
+		switch {
+		case m.src[m.pos] == '}':
+			m.pos++
+			b.WriteString("<mo>}</mo>")
+		case m.src[m.pos] == '&':
+			m.pos++
+		case m.atCommand("\\"):
+			m.pos += 2
+			b.WriteString(`<mspace linebreak="newline"/>`)
+		case m.atCommand("right"):
+			m.pos += len(`\right`)
+			b.WriteString(mathFence(m.readDelimiter()))
+		case m.atCommand("end"):
+			m.pos += len(`\end`)
+			m.readRawGroup()
+		}
+	}
+}
+
+func (m *mathParser) done() bool {
+	return m.pos >= len(m.src)
+}
+
+func (m *mathParser) skipSpace() {
+	for !m.done() && strings.IndexByte(" \t\n", m.src[m.pos]) >= 0 {
+		m.pos++
+	}
+}
+
+// atCommand reports whether the formula continues with the command \name.
+func (m *mathParser) atCommand(name string) bool {
+	if !strings.HasPrefix(m.src[m.pos:], `\`+name) {
+		return false
+	}
+	end := m.pos + 1 + len(name)
+	return !isLetter(name[len(name)-1]) || end >= len(m.src) || !isLetter(m.src[end])
+}
+
+// parseRow parses items up to the end of the formula or a "}", "&", "\\",
+// \right or \end, which it leaves for the caller.
+func (m *mathParser) parseRow() string {
+	var b strings.Builder
+	for {
+		m.skipSpace()
+		if m.done() || strings.IndexByte("}&", m.src[m.pos]) >= 0 ||
+			m.atCommand("\\") || m.atCommand("right") || m.atCommand("end") {
+			return b.String()
+		}
+		b.WriteString(m.parseItem())
+	}
+}
+
+// parseItem parses an atom together with its sub- and superscripts.
+func (m *mathParser) parseItem() string {
+	command := m.commandName()
+	base := m.parseAtom()
+	_, large := largeOperators[command]
+	underOver := m.display && (large || limitFunctions[command])
+
+	var sub, sup string
+	for {
+		m.skipSpace()
+		if m.atCommand("limits") {
+			m.pos += len(`\limits`)
+			underOver = true
+			continue
+		}
+		if m.atCommand("nolimits") {
+			m.pos += len(`\nolimits`)
+			underOver = false
+			continue
+		}
+		if m.done() {
+			break
+		}
+		if m.src[m.pos] == '_' && sub == "" {
+			m.pos++
+			sub = m.parseArgument()
+		} else if m.src[m.pos] == '^' && sup == "" {
+			m.pos++
+			sup = m.parseArgument()
+		} else if m.src[m.pos] == '\'' && sup == "" {
+			primes := ""
+			for !m.done() && m.src[m.pos] == '\'' {
+				primes += "′"
+				m.pos++
+			}
+			sup = "<mo>" + primes + "</mo>"
+		} else {
+			break
+		}
+	}
+
+	switch {
+	case sub != "" && sup != "" && underOver:
+		return "<munderover>" + base + sub + sup + "</munderover>"
+	case sub != "" && sup != "":
+		return "<msubsup>" + base + sub + sup + "</msubsup>"
+	case sub != "" && underOver:
+		return "<munder>" + base + sub + "</munder>"
+	case sub != "":
+		return "<msub>" + base + sub + "</msub>"
+	case sup != "" && underOver:
+		return "<mover>" + base + sup + "</mover>"
+	case sup != "":
+		return "<msup>" + base + sup + "</msup>"
+	}
+	return base
+}
+
+// commandName returns the name of the command at the current position, or
+// "" when there is none.
+func (m *mathParser) commandName() string {
+	if m.src[m.pos] != '\\' {
+		return ""
+	}
+	end := m.pos + 1
+	for end < len(m.src) && isLetter(m.src[end]) {
+		end++
+	}
+	return m.src[m.pos+1 : end]
+}
+
+// parseArgument parses the argument of a command or script: a group, or a
+// single digit or atom, as in "\frac12" or "x^2".
+func (m *mathParser) parseArgument() string {
+	m.skipSpace()
+	switch {
+	case m.done():
+		return "<mrow></mrow>"
+	case isDigit(m.src[m.pos]):
+		m.pos++
+		return "<mn>" + m.src[m.pos-1:m.pos] + "</mn>"
+	}
+	return m.parseAtom()
+}
+
+// parseAtom parses a single element without its scripts. Past maxMathDepth
+// it takes the rest of the formula as an error.
+func (m *mathParser) parseAtom() string {
+	if m.depth >= maxMathDepth {
+		rest := m.src[m.pos:]
+		m.pos = len(m.src)
+		return "<merror><mtext>" + escapeHTML(rest) + "</mtext></merror>"
+	}
+	m.depth++
+	defer func() { m.depth-- }()
+
+	c := m.src[m.pos]
+	switch {
+	case c == '{':
+		m.pos++
+		row := m.parseRow()
+		m.expect('}')
+		return "<mrow>" + row + "</mrow>"
+
+	case c == '^' || c == '_':
+		// A script with nothing before it.
+		return "<mrow></mrow>"
+
+	case c == '\\':
+		return m.parseCommand()
+
+	case isDigit(c) || c == '.' && m.pos+1 < len(m.src) && isDigit(m.src[m.pos+1]):
+		start := m.pos
+		for !m.done() && (isDigit(m.src[m.pos]) || m.src[m.pos] == '.' && m.pos+1 < len(m.src) && isDigit(m.src[m.pos+1])) {
+			m.pos++
+		}
+		return "<mn>" + m.src[start:m.pos] + "</mn>"
+
+	case isLetter(c):
+		m.pos++
+		return "<mi>" + string(c) + "</mi>"
+
+	case c >= utf8.RuneSelf:
+		r, size := utf8.DecodeRuneInString(m.src[m.pos:])
+		m.pos += size
+		return "<mi>" + escapeHTML(string(r)) + "</mi>"
+	}
+
+	m.pos++
+	switch c {
+	case '-':
+		return "<mo>−</mo>"
+	case '*':
+		return "<mo>∗</mo>"
+	case '~':
+		return `<mspace width="0.25em"/>`
+	case '(', ')', '[', ']', '|':
+		return `<mo stretchy="false">` + string(c) + "</mo>"
+	}
+	return "<mo>" + escapeHTML(string(c)) + "</mo>"
+}
+
+// parseCommand parses a command starting with a backslash.
+func (m *mathParser) parseCommand() string {
+	m.pos++
+	if m.done() {
+		return `<mo>\</mo>`
+	}
+
+	start := m.pos
+	if isLetter(m.src[m.pos]) {
+		for !m.done() && isLetter(m.src[m.pos]) {
+			m.pos++
+		}
+	} else {
+		m.pos++
+	}
+	name := m.src[start:m.pos]
+
+	if symbol, ok := greekLetters[name]; ok {
+		return "<mi>" + symbol + "</mi>"
+	}
+	if symbol, ok := upperGreekLetters[name]; ok {
+		return `<mi mathvariant="normal">` + symbol + "</mi>"
+	}
+	if symbol, ok := mathIdentifiers[name]; ok {
+		return "<mi>" + symbol + "</mi>"
+	}
+	if symbol, ok := mathOperators[name]; ok {
+		return "<mo>" + symbol + "</mo>"
+	}
+	if symbol, ok := largeOperators[name]; ok {
+		return `<mo largeop="true" movablelimits="true">` + symbol + "</mo>"
+	}
+	if symbol, ok := integrals[name]; ok {
+		return `<mo largeop="true">` + symbol + "</mo>"
+	}
+	if mathFunctions[name] || limitFunctions[name] {
+		return "<mi>" + name + "</mi>"
+	}
+	if accent, ok := mathAccents[name]; ok {
+		return `<mover accent="true">` + m.parseArgument() + "<mo>" + escapeHTML(accent) + "</mo></mover>"
+	}
+	if variant, ok := mathVariants[name]; ok {
+		return m.parseStyled(variant)
+	}
+	if width, ok := mathSpaces[name]; ok {
+		return fmt.Sprintf(`<mspace width="%s"/>`, width)
+	}
+	if ignoredCommands[name] {
+		return ""
+	}
+
+	switch name {
+	case "{", "}", "%", "$", "#", "&", "_":
+		return "<mo>" + escapeHTML(name) + "</mo>"
+	case "|":
+		return "<mo>‖</mo>"
+
+	case "frac", "dfrac", "tfrac":
+		return "<mfrac>" + m.parseArgument() + m.parseArgument() + "</mfrac>"
+
+	case "binom":
+		return `<mrow><mo>(</mo><mfrac linethickness="0">` + m.parseArgument() + m.parseArgument() + "</mfrac><mo>)</mo></mrow>"
+
+	case "sqrt":
+		m.skipSpace()
+		if !m.done() && m.src[m.pos] == '[' {
+			index := m.readRawUntil(']')
+			radicand := m.parseArgument()
+			inner := &mathParser{src: index, display: m.display}
+			return "<mroot>" + radicand + "<mrow>" + inner.parseTopLevel() + "</mrow></mroot>"
+		}
+		return "<msqrt>" + m.parseArgument() + "</msqrt>"
+
+	case "text", "textrm", "textit", "textbf", "mbox":
+		return "<mtext>" + escapeHTML(m.readRawGroup()) + "</mtext>"
+
+	case "operatorname":
+		return "<mi>" + escapeHTML(m.readRawGroup()) + "</mi>"
+
+	case "underline":
+		return `<munder accentunder="true">` + m.parseArgument() + "<mo>_</mo></munder>"
+
+	case "left":
+		open := m.readDelimiter()
+		row := m.parseRow()
+		close := ""
+		if m.atCommand("right") {
+			m.pos += len(`\right`)
+			close = m.readDelimiter()
+		}
+		return "<mrow>" + mathFence(open) + row + mathFence(close) + "</mrow>"
+
+	case "big", "Big", "bigg", "Bigg", "bigl", "Bigl", "biggl", "Biggl",
+		"bigr", "Bigr", "biggr", "Biggr", "bigm", "Bigm":
+		return `<mo stretchy="false">` + escapeHTML(m.readDelimiter()) + "</mo>"
+
+	case "begin":
+		return m.parseEnvironment(m.readRawGroup())
+	}
+
+	return `<merror><mtext>\` + escapeHTML(name) + "</mtext></merror>"
+}
+
+// parseStyled parses the argument of a font command such as \mathbb. A
+// plain run of letters becomes one identifier in that variant.
+func (m *mathParser) parseStyled(variant string) string {
+	m.skipSpace()
+	if !m.done() && m.src[m.pos] == '{' {
+		end := strings.IndexByte(m.src[m.pos:], '}')
+		if end > 1 && isLetters(m.src[m.pos+1:m.pos+end]) {
+			letters := m.src[m.pos+1 : m.pos+end]
+			m.pos += end + 1
+			return fmt.Sprintf(`<mi mathvariant="%s">%s</mi>`, variant, letters)
+		}
+	} else if !m.done() && isLetter(m.src[m.pos]) {
+		m.pos++
+		return fmt.Sprintf(`<mi mathvariant="%s">%s</mi>`, variant, m.src[m.pos-1:m.pos])
+	}
+	return fmt.Sprintf(`<mstyle mathvariant="%s">%s</mstyle>`, variant, m.parseArgument())
+}
+
+// parseEnvironment parses the cells of a matrix-like environment up to its
+// \end, as a table wrapped in the environment's fences.
+func (m *mathParser) parseEnvironment(name string) string {
+	fences, ok := matrixFences[name]
+	if !ok {
+		return `<merror><mtext>\begin{` + escapeHTML(name) + "}</mtext></merror>"
+	}
+	if name == "array" {
+		// The column specification.
+		m.readRawGroup()
+	}
+
+	var rows [][]string
+	row := []string{}
+	for {
+		row = append(row, m.parseRow())
+		if m.done() {
+			break
+		}
+		if m.src[m.pos] == '&' {
+			m.pos++
+			continue
+		}
+		if m.atCommand("\\") {
+			m.pos += 2
+			rows = append(rows, row)
+			row = []string{}
+			continue
+		}
+		if m.atCommand("end") {
+			m.pos += len(`\end`)
+			m.readRawGroup()
+			break
+		}
+		// A stray "}" or \right inside the environment.
+		if m.atCommand("right") {
+			m.pos += len(`\right`)
+			m.readDelimiter()
+		} else {
+			m.pos++
+		}
+	}
+	if len(row) > 1 || row[0] != "" {
+		rows = append(rows, row)
+	}
+
+	attributes := ""
+	switch name {
+	case "cases":
+		attributes = ` columnalign="left left"`
+	case "aligned", "align", "align*", "split":
+		attributes = ` columnalign="right left" columnspacing="0em"`
+	}
+
+	var b strings.Builder
+	b.WriteString("<mrow>")
+	b.WriteString(mathFence(fences[0]))
+	b.WriteString("<mtable" + attributes + ">")
+	for _, cells := range rows {
+		b.WriteString("<mtr>")
+		for _, cell := range cells {
+			b.WriteString("<mtd>" + cell + "</mtd>")
+		}
+		b.WriteString("</mtr>")
+	}
+	b.WriteString("</mtable>")
+	b.WriteString(mathFence(fences[1]))
+	b.WriteString("</mrow>")
+	return b.String()
+}
+
+// mathFence renders a stretchy fence, or nothing for an empty delimiter.
+func mathFence(delimiter string) string {
+	if delimiter == "" {
+		return ""
+	}
+	return `<mo fence="true" stretchy="true">` + escapeHTML(delimiter) + "</mo>"
+}
+
+// readDelimiter reads the delimiter after \left, \right or \big: a
+// character, or a command such as \langle or \{. "." stands for none.
+func (m *mathParser) readDelimiter() string {
+	m.skipSpace()
+	if m.done() {
+		return ""
+	}
+	if m.src[m.pos] != '\\' {
+		c := m.src[m.pos]
+		m.pos++
+		if c == '.' {
+			return ""
+		}
+		return string(c)
+	}
+
+	m.pos++
+	start := m.pos
+	for !m.done() && isLetter(m.src[m.pos]) {
+		m.pos++
+	}
+	if m.pos == start && !m.done() {
+		m.pos++
+	}
+	name := m.src[start:m.pos]
+	switch name {
+	case "{", "}":
+		return name
+	case "|":
+		return "‖"
+	}
+	return mathOperators[name]
+}
+
+// readRawGroup reads the source of a braced group without parsing it.
+func (m *mathParser) readRawGroup() string {
+	m.skipSpace()
+	if m.done() || m.src[m.pos] != '{' {
+		return ""
+	}
+	return m.readRawUntil('}')
+}
+
+// readRawUntil reads from the opening bracket at the current position to
+// its matching close, and returns what is between them.
+func (m *mathParser) readRawUntil(close byte) string {
+	open := m.src[m.pos]
+	depth := 0
+	for i := m.pos; i < len(m.src); i++ {
+		switch m.src[i] {
+		case '\\':
+			i++
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				content := m.src[m.pos+1 : i]
+				m.pos = i + 1
+				return content
+			}
+		}
+	}
+	content := m.src[m.pos+1:]
+	m.pos = len(m.src)
+	return content
+}
+
+func (m *mathParser) expect(c byte) {
+	if !m.done() && m.src[m.pos] == c {
+		m.pos++
+	}
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isLetters(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isLetter(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,62 @@
+package renderer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLatexToMathML(t *testing.T) {
+	tests := []struct {
+		name    string
+		tex     string
+		display bool
+		// mathml is the rendered formula inside <semantics>, without the
+		// annotation holding the source.
+		mathml string
+	}{
+		{"identifiers and operators", `a < b`, false, `<mrow><mi>a</mi><mo>&lt;</mo><mi>b</mi></mrow>`},
+		{"number", `12.5x`, false, `<mrow><mn>12.5</mn><mi>x</mi></mrow>`},
+		{"greek letters", `\alpha + \beta`, false, `<mrow><mi>α</mi><mo>+</mo><mi>β</mi></mrow>`},
+		{"fraction", `\frac{a}{b}`, false, `<mrow><mfrac><mrow><mi>a</mi></mrow><mrow><mi>b</mi></mrow></mfrac></mrow>`},
+		{"superscript", `x^2`, false, `<mrow><msup><mi>x</mi><mn>2</mn></msup></mrow>`},
+		{"sub- and superscript", `x_i^2`, false, `<mrow><msubsup><mi>x</mi><mi>i</mi><mn>2</mn></msubsup></mrow>`},
+		{"square root", `\sqrt{x}`, false, `<mrow><msqrt><mrow><mi>x</mi></mrow></msqrt></mrow>`},
+		{"nth root", `\sqrt[3]{x}`, false, `<mrow><mroot><mrow><mi>x</mi></mrow><mrow><mn>3</mn></mrow></mroot></mrow>`},
+		{"inline sum limits", `\sum_{i=1}^n i`, false,
+			`<mrow><msubsup><mo largeop="true" movablelimits="true">∑</mo><mrow><mi>i</mi><mo>=</mo><mn>1</mn></mrow><mi>n</mi></msubsup><mi>i</mi></mrow>`},
+		{"display sum limits", `\sum_{i=1}^n i`, true,
+			`<mrow><munderover><mo largeop="true" movablelimits="true">∑</mo><mrow><mi>i</mi><mo>=</mo><mn>1</mn></mrow><mi>n</mi></munderover><mi>i</mi></mrow>`},
+		{"integral limits stay beside", `\int_0^1 f`, true,
+			`<mrow><msubsup><mo largeop="true">∫</mo><mn>0</mn><mn>1</mn></msubsup><mi>f</mi></mrow>`},
+		{"matrix", `\begin{pmatrix} a & b \\ c & d \end{pmatrix}`, false,
+			`<mrow><mrow><mo fence="true" stretchy="true">(</mo><mtable><mtr><mtd><mi>a</mi></mtd><mtd><mi>b</mi></mtd></mtr><mtr><mtd><mi>c</mi></mtd><mtd><mi>d</mi></mtd></mtr></mtable><mo fence="true" stretchy="true">)</mo></mrow></mrow>`},
+		{"left and right", `\left( x \right)`, false,
+			`<mrow><mrow><mo fence="true" stretchy="true">(</mo><mi>x</mi><mo fence="true" stretchy="true">)</mo></mrow></mrow>`},
+		{"empty fence", `\left. x \right|`, false, `<mrow><mrow><mi>x</mi><mo fence="true" stretchy="true">|</mo></mrow></mrow>`},
+		{"text", `\text{if } x`, false, `<mrow><mtext>if </mtext><mi>x</mi></mrow>`},
+		{"font variant", `\mathbb{R}`, false, `<mrow><mi mathvariant="double-struck">R</mi></mrow>`},
+		{"unknown command", `\foo x`, false, `<mrow><merror><mtext>\foo</mtext></merror><mi>x</mi></mrow>`},
+		{"too deeply nested", strings.Repeat("{", 64) + "x}", false,
+			`<mrow>` + strings.Repeat("<mrow>", 64) + `<merror><mtext>x}</mtext></merror>` + strings.Repeat("</mrow>", 64) + `</mrow>`},
+		{"unclosed group", `{a`, false, `<mrow><mrow><mi>a</mi></mrow></mrow>`},
+	}
+
+	for _, test := range tests {
+		got := latexToMathML(test.tex, test.display, "")
+		_, body, _ := strings.Cut(got, "<semantics>")
+		body, annotation, _ := strings.Cut(body, "<annotation encoding=\"application/x-tex\">")
+		if body != test.mathml {
+			t.Errorf("%s: %s\nexpected: %s\ngot:      %s", test.name, test.tex, test.mathml, body)
+		}
+		if want := escapeHTML(test.tex) + "</annotation></semantics></math>"; annotation != want {
+			t.Errorf("%s: annotation %q, expected %q", test.name, annotation, want)
+		}
+	}
+}
+
+func TestLatexToMathMLAttributes(t *testing.T) {
+	got := latexToMathML("x", true, ` id="e"`)
+	if !strings.HasPrefix(got, `<math xmlns="http://www.w3.org/1998/Math/MathML" display="block" id="e">`) {
+		t.Errorf("unexpected math element %s", got)
+	}
+}
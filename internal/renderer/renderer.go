@@ -5,9 +5,31 @@ import (
 )
 
 type Options struct {
-	EscapeHTML             bool
-	HardLineBreak          bool
-	SoftLineBreak          bool
+	// EscapeHTML is kept for compatibility and has no effect: text, code
+	// and link destinations are always escaped, and only raw HTML from the
+	// document is let through, with UnsafeHTML.
+	EscapeHTML bool
+	// UnsafeHTML renders HTML blocks and inline raw HTML verbatim. Without
+	// it they are escaped and show up as text, and link and image
+	// destinations that could run script are left empty.
+	UnsafeHTML bool
+	// HardLineBreak renders hard line breaks (two trailing spaces or a
+	// trailing backslash) as <br />. Without it they render as plain
+	// line endings.
+	HardLineBreak bool
+	// SoftLineBreak renders every soft line break inside a paragraph as
+	// <br /> instead of a plain line ending.
+	SoftLineBreak bool
+	// HeadingPermalinks adds a permalink anchor, shown on hover, to every
+	// heading that has an ID.
+	HeadingPermalinks bool
+	// TOCMinLevel and TOCMaxLevel limit the headings in tables of contents.
+	// Zero means levels 1 and 6.
+	TOCMinLevel int
+	TOCMaxLevel int
+	// TOCSidebar adds the table of contents as a sidebar to the standalone
+	// page rendered with IncludeCSS.
+	TOCSidebar             bool
 	IncludeCSS             bool
 	CssFilePath            string
 	IncludeSyntaxHighlight bool
@@ -0,0 +1,325 @@
+package madopa
+
+import (
+	"strings"
+	"testing"
+)
+
+// conversionTest is a markdown input and the HTML it converts to.
+type conversionTest struct {
+	name     string
+	markdown string
+	html     string
+}
+
+// runConversionTests converts each test with p and a renderer with the
+// default options, which escape raw HTML.
+func runConversionTests(t *testing.T, p *Parser, tests []conversionTest) {
+	t.Helper()
+	r := &Renderer{}
+	for _, test := range tests {
+		got, err := p.Convert(test.markdown, r.NewHTMLRenderer())
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if got != test.html {
+			t.Errorf("%s\nmarkdown: %q\nexpected: %q\ngot:      %q", test.name, test.markdown, test.html, got)
+		}
+	}
+}
+
+func TestEscaping(t *testing.T) {
+	runConversionTests(t, &Parser{}, []conversionTest{
+		{"entity", "&lt;img src=x onerror=alert(1)&gt;", "<p>&lt;img src=x onerror=alert(1)&gt;</p>\n"},
+		{"backslash escape", `\<script\>`, "<p>&lt;script&gt;</p>\n"},
+		{"code span", "`<b>`", "<p><code>&lt;b&gt;</code></p>\n"},
+		{"code block", "    <b>", "<pre><code>&lt;b&gt;\n</code></pre>\n"},
+		{"raw HTML", "<b>bold</b>", "<p>&lt;b&gt;bold&lt;/b&gt;</p>\n"},
+		{"link title", `[a](/u "<x>")`, "<p><a href=\"/u\" title=\"&lt;x&gt;\">a</a></p>\n"},
+		{"javascript link", "[x](javascript:alert(1))", "<p><a href=\"\">x</a></p>\n"},
+		{"javascript autolink", "<javascript:alert(1)>", "<p><a href=\"\">javascript:alert(1)</a></p>\n"},
+		{"vbscript link", "[x](VBScript:msgbox)", "<p><a href=\"\">x</a></p>\n"},
+		{"file link", "[x](file:///etc/passwd)", "<p><a href=\"\">x</a></p>\n"},
+		{"data link", "[x](data:text/html,x)", "<p><a href=\"\">x</a></p>\n"},
+		{"data image", "![x](data:image/png;base64,AA==)", "<p><img src=\"data:image/png;base64,AA==\" alt=\"x\" /></p>\n"},
+	})
+}
+
+func TestFrontMatter(t *testing.T) {
+	runConversionTests(t, &Parser{}, []conversionTest{
+		{"yaml", "---\ntitle: Hello\n---\n# Title\n", "<h1 id=\"title\">Title</h1>\n"},
+		{"toml", "+++\ntitle = \"Hello\"\n+++\ntext\n", "<p>text</p>\n"},
+		{"json", "{\"title\": \"Hello\"}\ntext\n", "<p>text</p>\n"},
+		{"thematic breaks around markdown", "---\n\n# Title\n\nSome text.\n\n---\n",
+			"<hr />\n<h1 id=\"title\">Title</h1>\n<p>Some text.</p>\n<hr />\n"},
+		{"invalid toml", "+++\nnot toml\n+++\n", "<p>+++\nnot toml\n+++</p>\n"},
+	})
+
+	doc, err := (&Parser{}).Parse("---\ntitle: Hello\ntags: [a, b]\n---\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := Meta(doc)
+	if tags, _ := meta["tags"].([]any); meta["title"] != "Hello" || len(tags) != 2 {
+		t.Errorf("unexpected front matter %v", meta)
+	}
+}
+
+func TestMath(t *testing.T) {
+	runConversionTests(t, &Parser{}, []conversionTest{
+		{"off by default", "export PATH=$HOME/bin:$PATH", "<p>export PATH=$HOME/bin:$PATH</p>\n"},
+	})
+
+	p := &Parser{}
+	p.SetMath(true)
+	runConversionTests(t, p, []conversionTest{
+		{"inline", "$x$", "<p><math xmlns=\"http://www.w3.org/1998/Math/MathML\"><semantics><mrow><mi>x</mi></mrow>" +
+			"<annotation encoding=\"application/x-tex\">x</annotation></semantics></math></p>\n"},
+		{"block", "$$\nx\n$$", "<math xmlns=\"http://www.w3.org/1998/Math/MathML\" display=\"block\"><semantics><mrow><mi>x</mi></mrow>" +
+			"<annotation encoding=\"application/x-tex\">x</annotation></semantics></math>\n"},
+		{"prices", "$5 and $10", "<p>$5 and $10</p>\n"},
+		{"space before closer", "$a $b$", "<p>$a <math xmlns=\"http://www.w3.org/1998/Math/MathML\"><semantics><mrow><mi>b</mi></mrow>" +
+			"<annotation encoding=\"application/x-tex\">b</annotation></semantics></math></p>\n"},
+		{"code fence", "```math\nx\n```", "<math xmlns=\"http://www.w3.org/1998/Math/MathML\" display=\"block\"><semantics><mrow><mi>x</mi></mrow>" +
+			"<annotation encoding=\"application/x-tex\">x</annotation></semantics></math>\n"},
+	})
+}
+
+func TestAttributes(t *testing.T) {
+	runConversionTests(t, &Parser{}, []conversionTest{
+		{"heading", "# Title {#top .main}", "<h1 id=\"top\" class=\"main\">Title</h1>\n"},
+		{"paragraph", "text\n{.note}", "<p class=\"note\">text</p>\n"},
+		{"list", "- a\n- b\n{.list}", "<ul class=\"list\">\n<li>a</li>\n<li>b</li>\n</ul>\n"},
+		{"tight list paragraph", "- a\n  {.item}", "<ul>\n<li>\n<p class=\"item\">a</p>\n</li>\n</ul>\n"},
+		{"definition list", "Term\n: def\n{.dl}", "<dl class=\"dl\">\n<dt>Term</dt>\n<dd>def</dd>\n</dl>\n"},
+		{"block quote", "> quote\n{.q}", "<blockquote class=\"q\">\n<p>quote</p>\n</blockquote>\n"},
+		{"link", "[a](/u){target=_blank}", "<p><a href=\"/u\" target=\"_blank\">a</a></p>\n"},
+		{"span", "[text]{.red}", "<p><span class=\"red\">text</span></p>\n"},
+		{"code span", "`x`{.go}", "<p><code class=\"go\">x</code></p>\n"},
+		{"event handler dropped", "[a](/u){onclick=alert(1)}", "<p><a href=\"/u\">a</a></p>\n"},
+		{"link attributes kept", "[a](/u){href=/x}", "<p><a href=\"/u\">a</a></p>\n"},
+		{"image attributes kept", "![i](a.png \"t\"){src=b.png alt=y width=10}",
+			"<p><img src=\"a.png\" alt=\"i\" title=\"t\" width=\"10\" /></p>\n"},
+		{"list start kept", "3. a\n4. b\n{start=9}", "<ol start=\"3\">\n<li>a</li>\n<li>b</li>\n</ol>\n"},
+		{"after blank line", "text\n\n{.note}", "<p>text</p>\n<p>{.note}</p>\n"},
+	})
+}
+
+func TestFootnotes(t *testing.T) {
+	runConversionTests(t, &Parser{}, []conversionTest{
+		{"reference and definition", "a[^1]\n\n[^1]: Note.",
+			"<p>a<sup class=\"footnote-ref\"><a href=\"#fn-1\" id=\"fnref-1\">1</a></sup></p>\n" +
+				"<section class=\"footnotes\">\n<ol>\n<li id=\"fn-1\">\n" +
+				"<p>Note. <a href=\"#fnref-1\" class=\"footnote-backref\" aria-label=\"Back to reference 1\">↩</a></p>\n" +
+				"</li>\n</ol>\n</section>\n"},
+		{"repeated reference", "a[^n] b[^n]\n\n[^n]: Note.",
+			"<p>a<sup class=\"footnote-ref\"><a href=\"#fn-1\" id=\"fnref-1\">1</a></sup> " +
+				"b<sup class=\"footnote-ref\"><a href=\"#fn-1\" id=\"fnref-1-2\">1</a></sup></p>\n" +
+				"<section class=\"footnotes\">\n<ol>\n<li id=\"fn-1\">\n" +
+				"<p>Note. <a href=\"#fnref-1\" class=\"footnote-backref\" aria-label=\"Back to reference 1\">↩</a> " +
+				"<a href=\"#fnref-1-2\" class=\"footnote-backref\" aria-label=\"Back to reference 2\">↩<sup>2</sup></a></p>\n" +
+				"</li>\n</ol>\n</section>\n"},
+		{"undefined", "a[^x]", "<p>a[^x]</p>\n"},
+		{"bracket in label", "a[^[x]\n\n[^[x]: Note.", "<p>a[^[x]</p>\n<p>[^[x]: Note.</p>\n"},
+	})
+}
+
+func TestNestingDepth(t *testing.T) {
+	html, err := Convert(strings.Repeat(">", 40)+" a", (&Renderer{}).NewHTMLRenderer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(html, "<blockquote>"); n != 32 {
+		t.Errorf("%d nested block quotes, expected 32", n)
+	}
+	if want := "<p>" + strings.Repeat("&gt;", 8) + " a</p>"; !strings.Contains(html, want) {
+		t.Errorf("markers past the nesting limit not kept as text: %s", html)
+	}
+}
+
+func TestTaskLists(t *testing.T) {
+	const (
+		checked   = "<input type=\"checkbox\" class=\"task-list-item-checkbox\" disabled=\"\" checked=\"\" /> "
+		unchecked = "<input type=\"checkbox\" class=\"task-list-item-checkbox\" disabled=\"\" /> "
+	)
+	runConversionTests(t, &Parser{}, []conversionTest{
+		{"tight", "- [x] done\n- [ ] todo",
+			"<ul class=\"task-list\">\n<li class=\"task-list-item\">" + checked + "done</li>\n" +
+				"<li class=\"task-list-item\">" + unchecked + "todo</li>\n</ul>\n"},
+		{"loose", "- [X] done\n\n- [ ] todo",
+			"<ul class=\"task-list\">\n<li class=\"task-list-item\">\n<p>" + checked + "done</p>\n</li>\n" +
+				"<li class=\"task-list-item\">\n<p>" + unchecked + "todo</p>\n</li>\n</ul>\n"},
+		{"code fence", "- [x] ```\n  code\n  ```",
+			"<ul class=\"task-list\">\n<li class=\"task-list-item\">" + checked + "\n<pre><code>code\n</code></pre>\n</li>\n</ul>\n"},
+		{"heading", "- [ ] # Head",
+			"<ul class=\"task-list\">\n<li class=\"task-list-item\">" + unchecked + "\n<h1 id=\"head\">Head</h1>\n</li>\n</ul>\n"},
+		{"no content", "- [ ]", "<ul>\n<li>[ ]</li>\n</ul>\n"},
+		{"ordinary item", "- [ ]x", "<ul>\n<li>[ ]x</li>\n</ul>\n"},
+	})
+
+	doc, err := (&Parser{}).Parse("- [x] a\n- [ ] b\n- [x] ```\n  c\n  ```\n- d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if open, done := TaskCounts(doc); open != 1 || done != 2 {
+		t.Errorf("%d open and %d done tasks, expected 1 and 2", open, done)
+	}
+}
+
+func TestLinkify(t *testing.T) {
+	runConversionTests(t, &Parser{}, []conversionTest{
+		{"https", "see https://x.com/p now", "<p>see <a href=\"https://x.com/p\">https://x.com/p</a> now</p>\n"},
+		{"www", "www.x.com", "<p><a href=\"http://www.x.com\">www.x.com</a></p>\n"},
+		{"port", "https://x.com:8080/p", "<p><a href=\"https://x.com:8080/p\">https://x.com:8080/p</a></p>\n"},
+		{"trailing punctuation", "https://x.com/p.", "<p><a href=\"https://x.com/p\">https://x.com/p</a>.</p>\n"},
+		{"unmatched parenthesis", "(www.x.com/(a))", "<p>(<a href=\"http://www.x.com/(a)\">www.x.com/(a)</a>)</p>\n"},
+		{"trailing entity", "www.x.com/a&b;", "<p><a href=\"http://www.x.com/a\">www.x.com/a</a>&amp;b;</p>\n"},
+		{"single segment domain", "https://localhost/p", "<p>https://localhost/p</p>\n"},
+		{"inside a word", "xhttps://x.com", "<p>xhttps://x.com</p>\n"},
+		{"inside link text", "[see https://x.com](/u)", "<p><a href=\"/u\">see https://x.com</a></p>\n"},
+	})
+
+	p := &Parser{}
+	p.SetLinkify(false)
+	runConversionTests(t, p, []conversionTest{
+		{"disabled", "https://x.com", "<p>https://x.com</p>\n"},
+	})
+}
+
+func TestListIndentation(t *testing.T) {
+	runConversionTests(t, &Parser{}, []conversionTest{
+		{"four spaces", "- a\n    - b", "<ul>\n<li>a\n<ul>\n<li>b</li>\n</ul>\n</li>\n</ul>\n"},
+		{"tab", "- a\n\t- b", "<ul>\n<li>a\n<ul>\n<li>b</li>\n</ul>\n</li>\n</ul>\n"},
+		{"ordered content column", "1. a\n   - b", "<ol>\n<li>a\n<ul>\n<li>b</li>\n</ul>\n</li>\n</ol>\n"},
+		{"short of the content column", "1. a\n  - b", "<ol>\n<li>a</li>\n</ol>\n<ul>\n<li>b</li>\n</ul>\n"},
+	})
+
+	p := &Parser{}
+	p.SetFixedListIndent(2)
+	runConversionTests(t, p, []conversionTest{
+		{"fixed two columns", "1. a\n  - b", "<ol>\n<li>a\n<ul>\n<li>b</li>\n</ul>\n</li>\n</ol>\n"},
+		{"fixed unordered", "- a\n  - b", "<ul>\n<li>a\n<ul>\n<li>b</li>\n</ul>\n</li>\n</ul>\n"},
+	})
+}
+
+func TestInlineExtensions(t *testing.T) {
+	runConversionTests(t, &Parser{}, []conversionTest{
+		{"defaults", "~~removed~~ ==marked== H~2~O x^2^", "<p><del>removed</del> ==marked== H~2~O x^2^</p>\n"},
+	})
+
+	p := &Parser{}
+	p.SetHighlight(true)
+	p.SetSubscript(true)
+	p.SetSuperscript(true)
+	runConversionTests(t, p, []conversionTest{
+		{"all", "~~removed~~ ==marked== H~2~O x^2^",
+			"<p><del>removed</del> <mark>marked</mark> H<sub>2</sub>O x<sup>2</sup></p>\n"},
+		{"nested emphasis", "~~a *b*~~", "<p><del>a <em>b</em></del></p>\n"},
+		{"spaces inside", "~a b~ ^a b^", "<p><sub>a b</sub> <sup>a b</sup></p>\n"},
+		{"unmatched lengths", "~~a~ ==b=", "<p>~~a~ ==b=</p>\n"},
+	})
+
+	p = &Parser{}
+	p.SetStrikethrough(false)
+	runConversionTests(t, p, []conversionTest{
+		{"strikethrough disabled", "~~removed~~", "<p>~~removed~~</p>\n"},
+	})
+}
+
+func TestHeadingIDs(t *testing.T) {
+	runConversionTests(t, &Parser{}, []conversionTest{
+		{"slug", "# Hello, World!", "<h1 id=\"hello-world\">Hello, World!</h1>\n"},
+		{"duplicates", "# A\n## A\n### A", "<h1 id=\"a\">A</h1>\n<h2 id=\"a-1\">A</h2>\n<h3 id=\"a-2\">A</h3>\n"},
+		{"unicode", "### Ünïcode Straße", "<h3 id=\"ünïcode-straße\">Ünïcode Straße</h3>\n"},
+		{"custom", "# Custom {#my-id}", "<h1 id=\"my-id\">Custom</h1>\n"},
+		{"duplicate of custom", "# A {#a-1}\n# A\n# A\n# A", "<h1 id=\"a-1\">A</h1>\n<h1 id=\"a\">A</h1>\n" +
+			"<h1 id=\"a-2\">A</h1>\n<h1 id=\"a-3\">A</h1>\n"},
+		{"setext", "Title\n=====", "<h1 id=\"title\">Title</h1>\n"},
+	})
+
+	r := &Renderer{}
+	r.SetHeadingPermalinks(true)
+	got, err := (&Parser{}).Convert("## Title", r.NewHTMLRenderer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<h2 id=\"title\">Title <a class=\"heading-permalink\" href=\"#title\" aria-label=\"Permalink\">#</a></h2>\n"; got != want {
+		t.Errorf("permalink\nexpected: %q\ngot:      %q", want, got)
+	}
+}
+
+func TestTableOfContents(t *testing.T) {
+	runConversionTests(t, &Parser{}, []conversionTest{
+		{"placeholder", "[TOC]\n\n# A\n## B\n# C",
+			"<nav class=\"toc\">\n<ul>\n<li><a href=\"#a\">A</a>\n<ul>\n<li><a href=\"#b\">B</a></li>\n</ul>\n</li>\n" +
+				"<li><a href=\"#c\">C</a></li>\n</ul>\n</nav>\n<h1 id=\"a\">A</h1>\n<h2 id=\"b\">B</h2>\n<h1 id=\"c\">C</h1>\n"},
+		{"comment placeholder", "<!-- toc -->\n\n# A",
+			"<nav class=\"toc\">\n<ul>\n<li><a href=\"#a\">A</a></li>\n</ul>\n</nav>\n<h1 id=\"a\">A</h1>\n"},
+	})
+
+	r := &Renderer{}
+	r.SetTOCLevels(2, 2)
+	got, err := (&Parser{}).Convert("[TOC]\n\n# A\n## B\n### C", r.NewHTMLRenderer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<nav class=\"toc\">\n<ul>\n<li><a href=\"#b\">B</a></li>\n</ul>\n</nav>\n" +
+		"<h1 id=\"a\">A</h1>\n<h2 id=\"b\">B</h2>\n<h3 id=\"c\">C</h3>\n"; got != want {
+		t.Errorf("levels\nexpected: %q\ngot:      %q", want, got)
+	}
+
+	doc, err := (&Parser{}).Parse("# A\n## B\n### C\n# D")
+	if err != nil {
+		t.Fatal(err)
+	}
+	toc := TOC(doc, 1, 2)
+	if len(toc) != 2 || toc[0].Title != "A" || len(toc[0].Children) != 1 ||
+		toc[0].Children[0].ID != "b" || len(toc[0].Children[0].Children) != 0 || toc[1].Title != "D" {
+		t.Errorf("unexpected table of contents %+v", toc)
+	}
+}
+
+func TestAdmonitions(t *testing.T) {
+	const (
+		note    = "<p class=\"admonition-title\"><span class=\"admonition-icon\" aria-hidden=\"true\">ℹ️</span>"
+		warning = "<p class=\"admonition-title\"><span class=\"admonition-icon\" aria-hidden=\"true\">⚠️</span>"
+	)
+	runConversionTests(t, &Parser{}, []conversionTest{
+		{"alert", "> [!NOTE]\n> Be careful.",
+			"<div class=\"admonition admonition-note\">\n" + note + "Note</p>\n<p>Be careful.</p>\n</div>\n"},
+		{"alert with title", "> [!WARNING] Custom title\n> text",
+			"<div class=\"admonition admonition-warning\">\n" + warning + "Custom title</p>\n<p>text</p>\n</div>\n"},
+		{"fenced", ":::tip\nUse *this*.\n:::",
+			"<div class=\"admonition admonition-tip\">\n<p class=\"admonition-title\"><span class=\"admonition-icon\" aria-hidden=\"true\">💡</span>" +
+				"Tip</p>\n<p>Use <em>this</em>.</p>\n</div>\n"},
+		{"nested", ":::note Outer\n:::warning\ninner\n:::\n:::",
+			"<div class=\"admonition admonition-note\">\n" + note + "Outer</p>\n" +
+				"<div class=\"admonition admonition-warning\">\n" + warning + "Warning</p>\n<p>inner</p>\n</div>\n</div>\n"},
+		{"plain block quote", "> [link]\n> text", "<blockquote>\n<p>[link]\ntext</p>\n</blockquote>\n"},
+	})
+
+	p := &Parser{}
+	p.SetAdmonitions(false)
+	runConversionTests(t, p, []conversionTest{
+		{"disabled", "> [!NOTE]\n> text", "<blockquote>\n<p>[!NOTE]\ntext</p>\n</blockquote>\n"},
+	})
+}
+
+func TestDefinitionLists(t *testing.T) {
+	runConversionTests(t, &Parser{}, []conversionTest{
+		{"single", "Term\n: Definition", "<dl>\n<dt>Term</dt>\n<dd>Definition</dd>\n</dl>\n"},
+		{"several terms and definitions", "Term 1\nTerm 2\n: Def a\n: Def b",
+			"<dl>\n<dt>Term 1</dt>\n<dt>Term 2</dt>\n<dd>Def a</dd>\n<dd>Def b</dd>\n</dl>\n"},
+		{"loose with block content", "Term\n\n: Loose def\n\n    more",
+			"<dl>\n<dt>Term</dt>\n<dd>\n<p>Loose def</p>\n<p>more</p>\n</dd>\n</dl>\n"},
+		{"groups", "A\n: a\n\nB\n: b", "<dl>\n<dt>A</dt>\n<dd>a</dd>\n<dt>B</dt>\n<dd>b</dd>\n</dl>\n"},
+		{"no definition", "Term\n:not one", "<p>Term\n:not one</p>\n"},
+	})
+
+	p := &Parser{}
+	p.SetDefinitionLists(false)
+	runConversionTests(t, p, []conversionTest{
+		{"disabled", "Term\n: Definition", "<p>Term\n: Definition</p>\n"},
+	})
+}
@@ -5,6 +5,33 @@ import (
 	"github.com/shonnnoronha/madopa/internal/renderer"
 )
 
+// Document is a parsed markdown document.
+type Document = parser.Document
+
+// TaskCounts returns the number of open and completed task list items in doc.
+func TaskCounts(doc *Document) (open, done int) {
+	return doc.TaskCounts()
+}
+
+// Meta returns the front matter of doc: the values of its YAML, TOML or
+// JSON header keyed by name, or nil when it has none. Strings, numbers,
+// booleans, dates (as time.Time), lists ([]any) and nested tables
+// (map[string]any) keep their types.
+func Meta(doc *Document) map[string]any {
+	return doc.Meta
+}
+
+// TOCEntry is a heading in a table of contents, with the headings nested
+// under it as children.
+type TOCEntry = parser.TOCEntry
+
+// TOC returns the table of contents of doc: its top-level headings from
+// minLevel to maxLevel, each nested under the closest preceding heading of
+// a lower level.
+func TOC(doc *Document, minLevel, maxLevel int) []*TOCEntry {
+	return doc.TOC(minLevel, maxLevel)
+}
+
 type Renderer struct {
 	options renderer.Options
 }
@@ -13,6 +40,10 @@ func (r *Renderer) SetEscapeHTML(escapeHTML bool) {
 	r.options.EscapeHTML = escapeHTML
 }
 
+func (r *Renderer) SetUnsafeHTML(unsafeHTML bool) {
+	r.options.UnsafeHTML = unsafeHTML
+}
+
 func (r *Renderer) SetHardLineBreak(hardLineBreak bool) {
 	r.options.HardLineBreak = hardLineBreak
 }
@@ -21,6 +52,21 @@ func (r *Renderer) SetSoftLineBreak(softLineBreak bool) {
 	r.options.SoftLineBreak = softLineBreak
 }
 
+func (r *Renderer) SetHeadingPermalinks(headingPermalinks bool) {
+	r.options.HeadingPermalinks = headingPermalinks
+}
+
+// SetTOCLevels sets the heading levels included in rendered tables of
+// contents.
+func (r *Renderer) SetTOCLevels(minLevel, maxLevel int) {
+	r.options.TOCMinLevel = minLevel
+	r.options.TOCMaxLevel = maxLevel
+}
+
+func (r *Renderer) SetTOCSidebar(tocSidebar bool) {
+	r.options.TOCSidebar = tocSidebar
+}
+
 func (r *Renderer) SetIncludeCss(includeCss bool) {
 	r.options.IncludeCSS = includeCss
 }
@@ -37,8 +83,94 @@ func (r *Renderer) NewHTMLRenderer() renderer.Renderer {
 	return renderer.NewHTMLRenderer(&r.options)
 }
 
-func Convert(markdown string, renderer renderer.Renderer) (string, error) {
-	doc, err := parser.Parse(markdown)
+// Parser holds the markdown dialect used by Convert. The zero value parses
+// with the default extensions enabled.
+type Parser struct {
+	options *parser.Options
+}
+
+func (p *Parser) opts() *parser.Options {
+	if p.options == nil {
+		defaults := parser.DefaultOptions()
+		p.options = &defaults
+	}
+	return p.options
+}
+
+func (p *Parser) SetCommonMark(commonMark bool) {
+	p.opts().CommonMark = commonMark
+}
+
+func (p *Parser) SetTables(tables bool) {
+	p.opts().Tables = tables
+}
+
+func (p *Parser) SetTaskLists(taskLists bool) {
+	p.opts().TaskLists = taskLists
+}
+
+func (p *Parser) SetStrikethrough(strikethrough bool) {
+	p.opts().Strikethrough = strikethrough
+}
+
+func (p *Parser) SetHighlight(highlight bool) {
+	p.opts().Highlight = highlight
+}
+
+func (p *Parser) SetSubscript(subscript bool) {
+	p.opts().Subscript = subscript
+}
+
+func (p *Parser) SetSuperscript(superscript bool) {
+	p.opts().Superscript = superscript
+}
+
+func (p *Parser) SetFootnotes(footnotes bool) {
+	p.opts().Footnotes = footnotes
+}
+
+func (p *Parser) SetFrontMatter(frontMatter bool) {
+	p.opts().FrontMatter = frontMatter
+}
+
+func (p *Parser) SetHeadingIDs(headingIDs bool) {
+	p.opts().HeadingIDs = headingIDs
+}
+
+func (p *Parser) SetTOC(toc bool) {
+	p.opts().TOC = toc
+}
+
+func (p *Parser) SetAdmonitions(admonitions bool) {
+	p.opts().Admonitions = admonitions
+}
+
+func (p *Parser) SetMath(math bool) {
+	p.opts().Math = math
+}
+
+func (p *Parser) SetDefinitionLists(definitionLists bool) {
+	p.opts().DefinitionLists = definitionLists
+}
+
+func (p *Parser) SetAttributes(attributes bool) {
+	p.opts().Attributes = attributes
+}
+
+func (p *Parser) SetLinkify(linkify bool) {
+	p.opts().Linkify = linkify
+}
+
+func (p *Parser) SetFixedListIndent(width int) {
+	p.opts().FixedListIndent = width
+}
+
+func (p *Parser) Parse(markdown string) (*Document, error) {
+	return parser.ParseWithOptions(markdown, *p.opts())
+}
+
+func (p *Parser) Convert(markdown string, renderer renderer.Renderer) (string, error) {
+	doc, err := p.Parse(markdown)
 	if err != nil {
 		return "", err
 	}
@@ -50,3 +182,7 @@ func Convert(markdown string, renderer renderer.Renderer) (string, error) {
 
 	return html, nil
 }
+
+func Convert(markdown string, renderer renderer.Renderer) (string, error) {
+	return (&Parser{}).Convert(markdown, renderer)
+}
@@ -0,0 +1,103 @@
+package madopa
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// pathologicalTimeout bounds the time any pathological input may take. The
+// inputs are small enough that a linear parser handles each in a few
+// milliseconds; quadratic or exponential behaviour blows well past it.
+const pathologicalTimeout = 2 * time.Second
+
+// pathologicalTests are inputs that once made the parser take quadratic or
+// exponential time.
+var pathologicalTests = []struct {
+	name     string
+	markdown string
+}{
+	{"nested links", strings.Repeat("[", 5000) + "a" + strings.Repeat("](b)", 5000)},
+	{"nested images", strings.Repeat("![", 5000) + "a" + strings.Repeat("](b)", 5000)},
+	{"unclosed brackets", strings.Repeat("a [", 20000)},
+	{"bracket pairs", strings.Repeat("[a]", 20000)},
+	{"autolink closing parentheses", "https://x.com/" + strings.Repeat(")", 100000)},
+	{"autolink trailing entities", "https://x.com/" + strings.Repeat("&a;", 50000)},
+	{"nested block quotes", strings.Repeat(">", 4000) + " a"},
+	{"nested block quote lines", strings.Repeat(strings.Repeat("> ", 500)+"a\n", 100)},
+	{"nested lists", strings.Repeat("- ", 4000) + "a"},
+	{"nested ordered lists", strings.Repeat("1. ", 4000) + "a"},
+	{"nested admonitions", strings.Repeat(":::note\n", 2000) + "a\n" + strings.Repeat(":::\n", 2000)},
+	{"nested footnotes", strings.Repeat("[^a]: ", 2000) + "a"},
+	{"emphasis pairs", strings.Repeat("*a* ", 50000)},
+	{"nested emphasis", strings.Repeat("*a **a ", 20000) + "b" + strings.Repeat(" a** a*", 20000)},
+	{"unclosed emphasis", strings.Repeat("*a _b ", 50000)},
+	{"unclosed footnote references", strings.Repeat("[^", 100000)},
+	{"unclosed footnote labels", strings.Repeat("[^a", 100000)},
+	{"unclosed math", strings.Repeat("$a ", 100000)},
+	{"unclosed display math", strings.Repeat("$$a ", 100000)},
+	{"unclosed HTML comments", strings.Repeat("a <!--", 100000)},
+	{"unclosed processing instructions", strings.Repeat("a <?", 100000)},
+	{"unclosed CDATA sections", strings.Repeat("a <![CDATA[", 100000)},
+	{"unclosed declarations", strings.Repeat("a <!A", 100000)},
+	{"repeated headings", strings.Repeat("# a\n", 20000)},
+	{"unclosed link attributes", strings.Repeat("[a](b){", 30000)},
+	{"unclosed code span attributes", strings.Repeat("`x`{", 50000)},
+	{"unclosed heading attributes", "# " + strings.Repeat(" {", 100000) + "}"},
+	{"unclosed code spans", backtickRuns(2000)},
+	{"nested math groups", "$" + strings.Repeat(`\frac{`, 8000) + "$"},
+}
+
+// pathologicalDialects are the parser configurations every pathological
+// input is converted with.
+var pathologicalDialects = []struct {
+	name  string
+	setup func(p *Parser)
+}{
+	{"default", func(p *Parser) {}},
+	{"CommonMark", func(p *Parser) { p.SetCommonMark(true) }},
+	{"all extensions", func(p *Parser) {
+		p.SetHighlight(true)
+		p.SetSubscript(true)
+		p.SetSuperscript(true)
+		p.SetMath(true)
+	}},
+}
+
+func TestPathologicalInputs(t *testing.T) {
+	for _, test := range pathologicalTests {
+		for _, dialect := range pathologicalDialects {
+			p := &Parser{}
+			dialect.setup(p)
+			r := &Renderer{}
+			r.SetEscapeHTML(true)
+
+			done := make(chan error, 1)
+			start := time.Now()
+			go func() {
+				_, err := p.Convert(test.markdown, r.NewHTMLRenderer())
+				done <- err
+			}()
+
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Errorf("%s (%s): %v", test.name, dialect.name, err)
+				}
+				t.Logf("%-30s %-15s %v", test.name, dialect.name, time.Since(start))
+			case <-time.After(pathologicalTimeout):
+				t.Errorf("%s (%s): not converted within %v", test.name, dialect.name, pathologicalTimeout)
+			}
+		}
+	}
+}
+
+// backtickRuns returns runs of 1 to n backticks separated by letters, none
+// of which closes another.
+func backtickRuns(n int) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		b.WriteString("a" + strings.Repeat("`", i))
+	}
+	return b.String()
+}
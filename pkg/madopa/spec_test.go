@@ -0,0 +1,108 @@
+package madopa
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"testing"
+)
+
+// minSpecPassing is the number of CommonMark spec examples known to pass.
+// Raise it whenever conformance improves so regressions are caught.
+const minSpecPassing = 652
+
+// specExample is one entry of testdata/spec.json, the example dump of the
+// CommonMark 0.31.2 spec produced by its spec_tests.py --dump-tests.
+type specExample struct {
+	Markdown  string `json:"markdown"`
+	HTML      string `json:"html"`
+	Example   int    `json:"example"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Section   string `json:"section"`
+}
+
+type specResult struct {
+	passed int
+	failed []int
+}
+
+func TestCommonMarkSpec(t *testing.T) {
+	content, err := os.ReadFile("testdata/spec.json")
+	if err != nil {
+		t.Fatalf("reading spec examples: %v", err)
+	}
+
+	var examples []specExample
+	if err := json.Unmarshal(content, &examples); err != nil {
+		t.Fatalf("decoding spec examples: %v", err)
+	}
+
+	p := &Parser{}
+	p.SetCommonMark(true)
+
+	r := &Renderer{}
+	r.SetEscapeHTML(true)
+	r.SetHardLineBreak(true)
+	r.SetUnsafeHTML(true)
+
+	results := make(map[string]*specResult)
+	var sections []string
+	passed := 0
+
+	for _, example := range examples {
+		result, ok := results[example.Section]
+		if !ok {
+			result = &specResult{}
+			results[example.Section] = result
+			sections = append(sections, example.Section)
+		}
+
+		got, err := convertExample(p, r, example.Markdown)
+		if err != nil {
+			t.Errorf("example %d (%s): %v", example.Example, example.Section, err)
+			result.failed = append(result.failed, example.Example)
+			continue
+		}
+
+		if got != example.HTML {
+			result.failed = append(result.failed, example.Example)
+			if testing.Verbose() {
+				t.Logf("example %d (%s)\nmarkdown: %q\nexpected: %q\ngot:      %q",
+					example.Example, example.Section, example.Markdown, example.HTML, got)
+			}
+			continue
+		}
+
+		result.passed++
+		passed++
+	}
+
+	sort.Strings(sections)
+	for _, section := range sections {
+		result := results[section]
+		total := result.passed + len(result.failed)
+		if len(result.failed) == 0 {
+			t.Logf("%-45s %3d/%-3d", section, result.passed, total)
+		} else {
+			t.Logf("%-45s %3d/%-3d failed: %v", section, result.passed, total, result.failed)
+		}
+	}
+	t.Logf("%-45s %3d/%-3d", "total", passed, len(examples))
+
+	if passed < minSpecPassing {
+		t.Errorf("only %d spec examples pass, expected at least %d", passed, minSpecPassing)
+	}
+}
+
+// convertExample converts a single example, turning a parser panic into an
+// error so one bad example does not abort the whole run.
+func convertExample(p *Parser, r *Renderer, markdown string) (html string, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+	return p.Convert(markdown, r.NewHTMLRenderer())
+}